@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bmishra/courierInfo/mapview"
+	"github.com/golang/geo/s2"
+)
+
+// Arc styles for -arc-style
+const (
+	ArcStraight    = ""
+	ArcGreatCircle = "great-circle"
+	ArcBezier      = "bezier"
+)
+
+// BezierSteps is the number of legs a -arc-style bezier route is drawn
+// with.
+const BezierSteps = 32
+
+func checkArcStyle(style string) error {
+	switch style {
+	case ArcStraight, ArcGreatCircle, ArcBezier:
+		return nil
+	}
+
+	return fmt.Errorf("%w: arc style %q, expected %s or %s", ErrBadInput, style, ArcGreatCircle, ArcBezier)
+}
+
+// routePoints returns the polyline a route is drawn along.
+func routePoints(a, b s2.LatLng, opts options) []s2.LatLng {
+	switch opts.arcStyle {
+	case ArcGreatCircle:
+		return greatCircle(a, b)
+	case ArcBezier:
+		return bezierArc(a, b, opts.arcCurvature)
+	}
+
+	return []s2.LatLng{a, b}
+}
+
+// bezierArc returns points along a quadratic Bézier curve from a to b,
+// bowed to the north side of the route. The curve is built in web-mercator
+// space, which every render scales uniformly, so it is the same curve on
+// screen at any zoom. The control point sits curvature times the route
+// length off its middle: the bow grows with the route, keeping short
+// routes as flat as long ones look.
+func bezierArc(a, b s2.LatLng, curvature float64) []s2.LatLng {
+	x0, y0 := mapview.Mercator(a)
+	x2, y2 := mapview.Mercator(b)
+
+	// the short way around, as Viewport.Project draws it
+	dx := x2 - x0
+	if dx > 0.5 {
+		dx--
+	} else if dx < -0.5 {
+		dx++
+	}
+	x2 = x0 + dx
+	dy := y2 - y0
+
+	length := math.Hypot(dx, dy)
+	if length == 0 || curvature == 0 {
+		return []s2.LatLng{a, b}
+	}
+
+	// normal of the route, turned to point north (y grows southwards)
+	nx, ny := -dy/length, dx/length
+	if ny > 0 {
+		nx, ny = -nx, -ny
+	}
+	x1 := (x0+x2)/2 + nx*curvature*length
+	y1 := (y0+y2)/2 + ny*curvature*length
+
+	points := make([]s2.LatLng, 0, BezierSteps+1)
+	points = append(points, a)
+	for i := 1; i < BezierSteps; i++ {
+		t := float64(i) / BezierSteps
+		u := 1 - t
+		x := u*u*x0 + 2*u*t*x1 + t*t*x2
+		y := u*u*y0 + 2*u*t*y1 + t*t*y2
+		points = append(points, mapview.Unmercator(x, y))
+	}
+
+	return append(points, b)
+}
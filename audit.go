@@ -0,0 +1,190 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Statuses of an -audit-log run
+const (
+	AuditOK          = "ok"
+	AuditFailed      = "failed"
+	AuditInterrupted = "interrupted"
+	AuditDeadline    = "deadline"
+)
+
+// auditInput is one input of an -audit-log run and what came of it.
+type auditInput struct {
+	File    string `json:"file"`
+	SHA256  string `json:"sha256"`
+	Rows    int    `json:"rows"`
+	Routes  int    `json:"routes"`
+	Skipped int    `json:"skipped"`
+	Output  string `json:"output,omitempty"`
+}
+
+// auditRecord is the -audit-log line of a run. Fields keep this order and
+// options are sorted by name, so equal runs log equal lines but for the
+// time.
+type auditRecord struct {
+	Time    string            `json:"time"`
+	Status  string            `json:"status"`
+	Error   string            `json:"error,omitempty"`
+	Options map[string]string `json:"options"`
+	Inputs  []auditInput      `json:"inputs"`
+
+	path string
+}
+
+// auditRun is the record of the run in progress with -audit-log, logged
+// by terminate when the run fails, and by the -deadline and second
+// interrupt aborts, which run on their own goroutines, hence auditMu.
+var (
+	auditMu  sync.Mutex
+	auditRun *auditRecord
+)
+
+// newAuditRecord starts the record of a run with the flags set on the
+// command line or by the input's #courierinfo: directive.
+func newAuditRecord(path string) *auditRecord {
+	rec := &auditRecord{Options: map[string]string{}, Inputs: []auditInput{}, path: path}
+	flag.Visit(func(f *flag.Flag) {
+		rec.Options[f.Name] = f.Value.String()
+	})
+
+	return rec
+}
+
+// add records an input and its result; res is nil when the input failed.
+// The hash is the one taken while the run read the input.
+func (r *auditRecord) add(file string, res *fileResult, hasher *contentHasher) {
+	in := auditInput{File: file, SHA256: hasher.sum()}
+
+	if res != nil {
+		in.Output = res.Path
+		if res.Summary != nil {
+			in.Rows = res.Summary.RowCount
+			in.Routes = res.Summary.Routes
+			in.Skipped = res.Summary.Skipped
+		}
+	}
+	r.Inputs = append(r.Inputs, in)
+}
+
+// write appends the record as one JSON line. The file is opened with
+// O_APPEND and the line written in a single call, so the lines of runs
+// appending at the same time don't interleave.
+func (r *auditRecord) write(status string, cause error) error {
+	r.Time = time.Now().UTC().Format(time.RFC3339)
+	r.Status = status
+	if cause != nil {
+		r.Error = cause.Error()
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// startAudit begins the record of the run for -audit-log path.
+func startAudit(path string) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	auditRun = newAuditRecord(path)
+}
+
+// addAudit records an input of the run, when -audit-log is set.
+func addAudit(file string, res *fileResult, hasher *contentHasher) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if auditRun != nil {
+		auditRun.add(file, res, hasher)
+	}
+}
+
+// finishAudit logs the run, when -audit-log is set, warning rather than
+// failing when the log can't be written. Only the first call logs.
+func finishAudit(status string, cause error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	if auditRun == nil {
+		return
+	}
+
+	rec := auditRun
+	auditRun = nil
+	if err := rec.write(status, cause); err != nil {
+		fmt.Println(fmt.Sprintf("Warning: -audit-log %s not written: %v", rec.path, err))
+	}
+}
+
+// contentHasher takes the SHA-256 of an input as the run reads it, so
+// -audit-log doesn't read the input again.
+type contentHasher struct {
+	h    hash.Hash
+	done bool
+}
+
+func newContentHasher() *contentHasher {
+	return &contentHasher{h: sha256.New()}
+}
+
+// reader tees r into the hash, starting it afresh for a new pass over the
+// input. A nil hasher returns r as is.
+func (c *contentHasher) reader(r io.Reader) io.Reader {
+	if c == nil {
+		return r
+	}
+
+	c.h.Reset()
+	c.done = false
+	return io.TeeReader(r, c.h)
+}
+
+// finish reads what is left of r, as returned by reader, so the hash
+// covers the whole input even when the run stopped early, e.g. on -limit.
+func (c *contentHasher) finish(r io.Reader) error {
+	if c == nil {
+		return nil
+	}
+
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return err
+	}
+	c.done = true
+	return nil
+}
+
+// sum is the hex hash, or why there is none when the input wasn't read to
+// the end.
+func (c *contentHasher) sum() string {
+	if c == nil || !c.done {
+		return "unread: the run stopped before the end of the input"
+	}
+
+	return hex.EncodeToString(c.h.Sum(nil))
+}
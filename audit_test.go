@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContentHasherDuringRead(t *testing.T) {
+	sum := sha256.Sum256([]byte(sampleCSV))
+	want := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name  string
+		setup func(*options)
+	}{
+		{name: "whole read", setup: func(o *options) {}},
+		{name: "stopped by -limit", setup: func(o *options) { o.limit = 1 }},
+		{name: "-byte-end shard", setup: func(o *options) { o.byteEnd = 100 }},
+		{name: "-name-by-hash reads first", setup: func(o *options) { o.nameByHash = true }},
+	}
+
+	for _, tt := range tests {
+		opts := testOptions(t)
+		opts.filename = filepath.Join(t.TempDir(), "in.csv")
+		if err := ioutil.WriteFile(opts.filename, []byte(sampleCSV), 0644); err != nil {
+			t.Fatal(err)
+		}
+		opts.hasher = newContentHasher()
+		tt.setup(&opts)
+
+		if opts.nameByHash {
+			if _, err := inputHash(opts.filename, opts); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, _, err := markLocations(opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := opts.hasher.sum(); got != want {
+			t.Errorf("%s: sum %s, want %s", tt.name, got, want)
+		}
+	}
+}
+
+func TestContentHasherUnread(t *testing.T) {
+	c := newContentHasher()
+	c.reader(strings.NewReader(sampleCSV))
+	if got := c.sum(); !strings.HasPrefix(got, "unread:") {
+		t.Errorf("sum before finish = %s", got)
+	}
+
+	var none *contentHasher
+	if r := strings.NewReader("x"); none.reader(r) != r || none.finish(r) != nil {
+		t.Error("nil hasher isn't a no-op")
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s2"
+)
+
+// BufferSegments is the number of polygon edges approximating a buffer
+// circle.
+const BufferSegments = 64
+
+// circlePolygon returns the points at radiusKm around center, so the
+// buffer follows the map projection like any other area.
+func circlePolygon(center s2.LatLng, radiusKm float64) []s2.LatLng {
+	lat1, lng1 := center.Lat.Radians(), center.Lng.Radians()
+	d := radiusKm * 1000 / EarthRadiusMeters
+
+	points := make([]s2.LatLng, 0, BufferSegments)
+	for i := 0; i < BufferSegments; i++ {
+		bearing := 2 * math.Pi * float64(i) / BufferSegments
+		lat := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(bearing))
+		lng := lng1 + math.Atan2(math.Sin(bearing)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat))
+		points = append(points, s2.LatLngFromDegrees(lat*180/math.Pi, lng*180/math.Pi))
+	}
+
+	return points
+}
+
+// bufferArea returns the translucent catchment circle around a source
+// marker, filled with c at alpha (0-1).
+func bufferArea(center s2.LatLng, radiusKm float64, c color.RGBA, alpha float64) *sm.Area {
+	fill := color.NRGBA{c.R, c.G, c.B, uint8(math.Round(255 * alpha))}
+	edge := color.NRGBA{c.R, c.G, c.B, 0xff}
+
+	return sm.NewArea(circlePolygon(center, radiusKm), edge, fill, 1.0)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/fogleman/gg"
+	"github.com/golang/geo/s2"
+)
+
+// Centroid methods
+const (
+	CentroidMean   = "mean"
+	CentroidMedian = "median"
+)
+
+// median iterations stop once a step moves less than this, in unit-sphere
+// distance (about 6 mm on Earth)
+const medianTolerance = 1e-9
+
+type vec3 struct{ x, y, z float64 }
+
+func toVec(ll s2.LatLng) vec3 {
+	lat, lng := ll.Lat.Radians(), ll.Lng.Radians()
+	return vec3{math.Cos(lat) * math.Cos(lng), math.Cos(lat) * math.Sin(lng), math.Sin(lat)}
+}
+
+func (v vec3) latLng() s2.LatLng {
+	lat := math.Atan2(v.z, math.Hypot(v.x, v.y))
+	lng := math.Atan2(v.y, v.x)
+	return s2.LatLngFromDegrees(lat*180/math.Pi, lng*180/math.Pi)
+}
+
+func (v vec3) dist(w vec3) float64 {
+	return math.Sqrt((v.x-w.x)*(v.x-w.x) + (v.y-w.y)*(v.y-w.y) + (v.z-w.z)*(v.z-w.z))
+}
+
+// centroid returns the center of the points: the mean of their positions
+// on the globe, or the geometric median found with Weiszfeld's algorithm,
+// which is less pulled by outliers. Working on unit vectors keeps both
+// correct across the antimeridian.
+func centroid(points []s2.LatLng, method string) (s2.LatLng, bool) {
+	if len(points) == 0 {
+		return s2.LatLng{}, false
+	}
+
+	vs := make([]vec3, len(points))
+	var c vec3
+	for i, p := range points {
+		vs[i] = toVec(p)
+		c.x += vs[i].x
+		c.y += vs[i].y
+		c.z += vs[i].z
+	}
+	n := float64(len(vs))
+	c = vec3{c.x / n, c.y / n, c.z / n}
+
+	if method == CentroidMedian {
+		for iter := 0; iter < 1000; iter++ {
+			var next vec3
+			var weights float64
+			for _, v := range vs {
+				d := v.dist(c)
+				if d < medianTolerance {
+					// on a data point: keep it rather than divide by zero
+					continue
+				}
+				next.x += v.x / d
+				next.y += v.y / d
+				next.z += v.z / d
+				weights += 1 / d
+			}
+			if weights == 0 {
+				break
+			}
+
+			next = vec3{next.x / weights, next.y / weights, next.z / weights}
+			moved := next.dist(c)
+			c = next
+			if moved < medianTolerance {
+				break
+			}
+		}
+	}
+
+	if c.x == 0 && c.y == 0 && c.z == 0 {
+		// points cancel out, e.g. exact antipodes
+		return s2.LatLng{}, false
+	}
+
+	return c.latLng(), true
+}
+
+// drawCentroidLabel writes the centroid coordinates next to its marker.
+func drawCentroidLabel(img image.Image, ll s2.LatLng, vp Viewport, t theme) image.Image {
+	dc := useTextFont(gg.NewContextForImage(img))
+	x, y := vp.Project(ll)
+	dc.SetColor(t.Text)
+	dc.DrawStringAnchored(fmt.Sprintf("%.5f,%.5f", ll.Lat.Degrees(), ll.Lng.Degrees()), x+8, y, 0, 0.5)
+
+	return dc.Image()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s2"
+)
+
+// chainState is the trail chain mode is extending: only the current one is
+// held, so memory doesn't grow with the number of shipments.
+type chainState struct {
+	id      string
+	row     int
+	points  []s2.LatLng
+	records [][]string // the row of each point, for -size-col
+
+	trails   int
+	segments int
+	ids      map[string]bool
+}
+
+// chain adds a row to chain mode: its source and destination extend the
+// previous row's trail while the -id-col value stays the same, and start a
+// new one when it changes. Unlike trail mode rows aren't grouped, so an ID
+// that comes back later starts another trail. It reports false once
+// -max-markers is reached.
+func (p *plotter) chain(rt route) bool {
+	opts := p.opts
+	c := &p.chained
+
+	id := ""
+	if opts.idCol < len(rt.Record) {
+		id = strings.TrimSpace(rt.Record[opts.idCol])
+	}
+	if id == "" {
+		p.flushChain()
+		p.pass(rt.Row, "no -id-col shipment ID")
+		return true
+	}
+
+	if id != c.id {
+		p.flushChain()
+		c.id, c.row = id, rt.Row
+	}
+
+	var added []s2.LatLng
+	for _, ll := range []s2.LatLng{rt.Src, rt.Dst} {
+		if len(c.points) == 0 || c.points[len(c.points)-1] != ll {
+			added = append(added, ll)
+		}
+	}
+	if markerCapReached(p.lyr, len(added), rt.Row, opts) {
+		p.flushChain()
+		return false
+	}
+
+	for _, ll := range added {
+		if n := len(c.points); n > 0 {
+			p.sum.TotalDistance += distanceMeters(c.points[n-1], ll, opts.distanceModel)
+		}
+		c.points = append(c.points, ll)
+		c.records = append(c.records, rt.Record)
+	}
+
+	p.diagnose(routeDiag(rt, nil))
+	p.sum.Routes++
+	return true
+}
+
+// flushChain draws the current trail, if any, and forgets it.
+func (p *plotter) flushChain() {
+	opts := p.opts
+	c := &p.chained
+	if len(c.points) == 0 {
+		c.id = ""
+		return
+	}
+
+	hue := trailColor(c.trails)
+	for j, ll := range c.points {
+		size := opts.markerSize
+		if j == 0 || j == len(c.points)-1 {
+			size *= 1.5
+		}
+		m := sm.NewMarker(ll, hue, size)
+		p.addMarker(m, c.records[j])
+		p.lyr.setID(m, fmt.Sprintf("%s.%d.%d", c.id, c.row, j))
+	}
+
+	segments := [][]s2.LatLng{c.points}
+	if opts.wrap {
+		segments = splitPolyline(c.points)
+	}
+	for _, segment := range segments {
+		p.lyr.addPath(sm.NewPath(segment, hue, 2))
+	}
+
+	if c.ids == nil {
+		c.ids = map[string]bool{}
+	}
+	c.ids[c.id] = true
+	p.lyr.sources = append(p.lyr.sources, c.points[0])
+	c.trails++
+	c.segments += len(c.points) - 1
+
+	c.id, c.points, c.records = "", nil, nil
+}
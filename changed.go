@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io/ioutil"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s2"
+)
+
+// changedMoved colors markers -changed-since finds moved; new ones use the
+// theme's source color.
+var changedMoved = color.RGBA{0xff, 0x7f, 0x00, 0xff}
+
+// basePoint is a point feature of a previous run's -geojson export.
+type basePoint struct {
+	ID  string
+	Pos s2.LatLng
+}
+
+// readBasePoints reads the point features of a GeoJSON file; lines and
+// other geometries are ignored.
+func readBasePoints(path string) ([]basePoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fc struct {
+		Features []struct {
+			ID       string `json:"id"`
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, fmt.Errorf("%w: -changed-since %s: %v", ErrBadInput, path, err)
+	}
+
+	var points []basePoint
+	for _, f := range fc.Features {
+		if f.Geometry.Type != "Point" {
+			continue
+		}
+
+		var lngLat []float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &lngLat); err != nil || len(lngLat) < 2 {
+			return nil, fmt.Errorf("%w: -changed-since %s: point %q has bad coordinates", ErrBadInput, path, f.ID)
+		}
+		points = append(points, basePoint{ID: f.ID, Pos: s2.LatLngFromDegrees(lngLat[1], lngLat[0])})
+	}
+
+	return points, nil
+}
+
+// changedLayer keeps the markers that are new or moved since the
+// -changed-since GeoJSON of an earlier run. Markers are paired by ID when
+// both runs have it, which needs IDs stable across runs such as -id-col,
+// and otherwise by position within the -precision tolerance, as diff mode
+// matches them. Moved markers get a line from where they were.
+func changedLayer(lyr *layer, opts options) (*layer, error) {
+	base, err := readBasePoints(opts.changedSince)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[string]s2.LatLng{}
+	for _, p := range base {
+		if p.ID != "" {
+			byID[p.ID] = p.Pos
+		}
+	}
+
+	// points without an ID in the current run are left to position matching
+	current := map[string]bool{}
+	for _, m := range lyr.markers {
+		current[lyr.ids[m]] = true
+	}
+	var unpaired []s2.LatLng
+	for _, p := range base {
+		if p.ID == "" || !current[p.ID] {
+			unpaired = append(unpaired, p.Pos)
+		}
+	}
+
+	tolerance := opts.precision.tolerance()
+	idx := newPointIndex(unpaired, tolerance)
+
+	out := &layer{}
+	added, moved, unchanged := 0, 0, 0
+	for _, m := range lyr.markers {
+		id := lyr.ids[m]
+		if prev, ok := byID[id]; ok && id != "" {
+			if distanceMeters(prev, m.Position, DistanceSpherical) <= tolerance {
+				unchanged++
+				continue
+			}
+			moved++
+			out.addPath(sm.NewPath([]s2.LatLng{prev, m.Position}, changedMoved, 1))
+			marker := sm.NewMarker(m.Position, changedMoved, opts.markerSize)
+			out.addMarker(marker)
+			out.setID(marker, id)
+			continue
+		}
+
+		if idx.match(m.Position, tolerance) {
+			unchanged++
+			continue
+		}
+		added++
+		marker := sm.NewMarker(m.Position, opts.theme.Source, opts.markerSize)
+		out.addMarker(marker)
+		if id != "" {
+			out.setID(marker, id)
+		}
+	}
+
+	fmt.Println(fmt.Sprintf("Changed since %s: added %d, moved %d, unchanged %d",
+		opts.changedSince, added, moved, unchanged))
+
+	return out, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s2"
+)
+
+// RegionNameProp is the feature property choropleth mode names a -mask
+// region by.
+const RegionNameProp = "name"
+
+// namedRegion is one feature of a choropleth -mask: its polygons and the
+// name it is reported under.
+type namedRegion struct {
+	Name string
+	Area mask
+}
+
+// loadRegions reads the Polygon and MultiPolygon features of a GeoJSON
+// file, one region each, named by their "name" property or, lacking one,
+// by their position in the file.
+func loadRegions(filename string) ([]namedRegion, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj struct {
+		Features []struct {
+			geoJSONInput
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("%w: mask %s: %v", ErrBadInput, filename, err)
+	}
+
+	var regions []namedRegion
+	for i, f := range obj.Features {
+		var area mask
+		if err := area.add(f.geoJSONInput); err != nil {
+			return nil, fmt.Errorf("%w: mask %s: %v", ErrBadInput, filename, err)
+		}
+		if len(area) == 0 {
+			continue
+		}
+
+		name, _ := f.Properties[RegionNameProp].(string)
+		if name == "" {
+			name = fmt.Sprintf("region %d", i+1)
+		}
+		regions = append(regions, namedRegion{Name: name, Area: area})
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("%w: mask %s has no Polygon or MultiPolygon features to count in", ErrBadInput, filename)
+	}
+
+	return regions, nil
+}
+
+// regionCount is one row of the choropleth table.
+type regionCount struct {
+	Region string
+	Count  int
+}
+
+// countRegions counts the points inside each region, in region order. A
+// point in overlapping regions counts in each.
+func countRegions(regions []namedRegion, points []s2.LatLng) []regionCount {
+	counts := make([]regionCount, len(regions))
+	for i, r := range regions {
+		counts[i].Region = r.Name
+		for _, ll := range points {
+			if r.Area.contains(ll) {
+				counts[i].Count++
+			}
+		}
+	}
+
+	return counts
+}
+
+// choroplethLayer shades each region from green (fewest points) to red
+// (most); regions without any are only outlined. Holes aren't cut out of
+// the fill, go-staticmaps areas have none.
+func choroplethLayer(regions []namedRegion, counts []regionCount, t theme) *layer {
+	max := 0
+	for _, c := range counts {
+		if c.Count > max {
+			max = c.Count
+		}
+	}
+
+	lyr := &layer{}
+	for i, r := range regions {
+		fill := color.NRGBA{0, 0, 0, 0}
+		if counts[i].Count > 0 {
+			c := gradientColor(float64(counts[i].Count) / float64(max))
+			fill = color.NRGBA{c.R, c.G, c.B, 0xb0}
+		}
+
+		for _, p := range r.Area {
+			if len(p) == 0 {
+				continue
+			}
+			positions := make([]s2.LatLng, 0, len(p[0]))
+			for _, lngLat := range p[0] {
+				positions = append(positions, s2.LatLngFromDegrees(lngLat[1], lngLat[0]))
+			}
+			lyr.addArea(sm.NewArea(positions, t.Text, fill, 1))
+		}
+	}
+
+	return lyr
+}
+
+// writeRegionCounts writes the counts as a table, busiest region first.
+func writeRegionCounts(w io.Writer, counts []regionCount) error {
+	sorted := append([]regionCount(nil), counts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Count > sorted[j].Count })
+
+	width := len("Region")
+	for _, c := range sorted {
+		if len(c.Region) > width {
+			width = len(c.Region)
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "%-*s  %s\n", width, "Region", "Count"); err != nil {
+		return err
+	}
+	for _, c := range sorted {
+		if _, err := fmt.Fprintf(w, "%-*s  %d\n", width, c.Region, c.Count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
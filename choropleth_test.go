@@ -0,0 +1,70 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+// twoSquares is a FeatureCollection of two unit squares side by side.
+const twoSquares = `{"type": "FeatureCollection", "features": [
+	{"type": "Feature", "properties": {"name": "west"},
+	 "geometry": {"type": "Polygon", "coordinates": [[[0,0],[1,0],[1,1],[0,1],[0,0]]]}},
+	{"type": "Feature", "properties": {},
+	 "geometry": {"type": "Polygon", "coordinates": [[[1,0],[2,0],[2,1],[1,1],[1,0]]]}}
+]}`
+
+func TestChoropleth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regions.geojson")
+	if err := os.WriteFile(path, []byte(twoSquares), 0644); err != nil {
+		t.Fatal(err)
+	}
+	regions, err := loadRegions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		points []s2.LatLng
+		counts []regionCount
+		fills  []color.RGBA // over white
+	}{
+		{
+			name:   "busiest region red, empty one unfilled",
+			points: []s2.LatLng{s2.LatLngFromDegrees(0.5, 0.5), s2.LatLngFromDegrees(0.2, 0.7)},
+			counts: []regionCount{{"west", 2}, {"region 2", 0}},
+			fills:  []color.RGBA{{0xff, 0x4f, 0x4f, 0xff}, {0xff, 0xff, 0xff, 0xff}},
+		},
+		{
+			name:   "outside every region",
+			points: []s2.LatLng{s2.LatLngFromDegrees(5, 5), s2.LatLngFromDegrees(0.5, 1.5)},
+			counts: []regionCount{{"west", 0}, {"region 2", 1}},
+			fills:  []color.RGBA{{0xff, 0xff, 0xff, 0xff}, {0xff, 0x4f, 0x4f, 0xff}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := countRegions(regions, tt.points)
+			for i, c := range counts {
+				if c != tt.counts[i] {
+					t.Errorf("region %d counted %v, want %v", i, c, tt.counts[i])
+				}
+			}
+
+			lyr := choroplethLayer(regions, counts, testOptions(t).theme)
+			if len(lyr.areas) != len(tt.fills) {
+				t.Fatalf("%d areas, want %d", len(lyr.areas), len(tt.fills))
+			}
+			for i, a := range lyr.areas {
+				if got := overWhite(a.Fill); got != tt.fills[i] {
+					t.Errorf("region %d over white is %v, want %v", i, got, tt.fills[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "fmt"
+
+// clipMarkers drops the markers drawn entirely outside the vp image, with
+// their IDs and glyphs, and returns how many it dropped. A pin reaches
+// its size above its position and half its size to each side.
+func clipMarkers(lyr *layer, vp Viewport) int {
+	kept := lyr.markers[:0]
+	clipped := 0
+	for _, m := range lyr.markers {
+		x, y := vp.Project(m.Position)
+		r := m.Size
+		if x+r < 0 || x-r > float64(vp.Width) || y+r < 0 || y-2*r > float64(vp.Height) {
+			clipped++
+			delete(lyr.ids, m)
+			delete(lyr.glyphs, m)
+			delete(lyr.props, m)
+			continue
+		}
+		kept = append(kept, m)
+	}
+
+	// clear the tail so the dropped markers can be freed
+	for i := len(kept); i < len(lyr.markers); i++ {
+		lyr.markers[i] = nil
+	}
+	lyr.markers = kept
+
+	return clipped
+}
+
+// clipToView applies -clip-to-view once the view is known. Auto-fit frames
+// every marker in the view, so it only drops markers with -center,
+// -fixed-zoom, -focus-percentile or -base-image.
+func clipToView(lyr *layer, vp Viewport, opts options) {
+	if !opts.clipToView {
+		return
+	}
+
+	n := clipMarkers(lyr, vp)
+	fmt.Println(fmt.Sprintf("Clipped %d markers outside the %dx%d view", n, vp.Width, vp.Height))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// namedColors are accepted wherever a color flag is parsed.
+var namedColors = map[string]color.RGBA{
+	"black": {0x00, 0x00, 0x00, 0xff},
+	"white": {0xff, 0xff, 0xff, 0xff},
+	"red":   {0xff, 0x00, 0x00, 0xff},
+	"green": {0x00, 0xff, 0x00, 0xff},
+	"blue":  {0x00, 0x00, 0xff, 0xff},
+	"gray":  {0x80, 0x80, 0x80, 0xff},
+}
+
+// parseColor parses a color name or a #rgb, #rrggbb or #rrggbbaa hex value.
+func parseColor(value string) (color.RGBA, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if c, ok := namedColors[value]; ok {
+		return c, nil
+	}
+
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.RGBA{}, fmt.Errorf("%w: invalid color %q", ErrBadInput, value)
+	}
+
+	return color.RGBA{uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)}, nil
+}
+
+// paletteColor picks a palette color by hashing key, so a value gets the
+// same color in every file and run. Distinct keys can collide on the same
+// color once there are more keys than palette entries (or by chance
+// before); pin such keys with an explicit mapping.
+func paletteColor(key string) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return groupPalette[h.Sum32()%uint32(len(groupPalette))]
+}
+
+// parseColorMapping parses "key:#hex,key:#hex" pairs. A value starting with
+// @ names a file holding the pairs, one per line or comma separated.
+func parseColorMapping(spec string) (map[string]color.RGBA, error) {
+	if strings.HasPrefix(spec, "@") {
+		data, err := ioutil.ReadFile(spec[1:])
+		if err != nil {
+			return nil, err
+		}
+		spec = strings.Replace(string(data), "\n", ",", -1)
+	}
+
+	mapping := map[string]color.RGBA{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("%w: color mapping %q, expected key:color", ErrBadInput, entry)
+		}
+
+		c, err := parseColor(entry[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		mapping[strings.TrimSpace(entry[:i])] = c
+	}
+
+	return mapping, nil
+}
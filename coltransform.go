@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SwapLatLng is the -transform-col operation that swaps a pair's numbers.
+const SwapLatLng = "swap_latlng"
+
+// cellOp rewrites the numbers of one cell, a pair or a single value.
+type cellOp func(nums []float64) []float64
+
+// cellTransforms are the -transform-col operations by column index, run in
+// order on every data row before any cell is parsed.
+type cellTransforms map[int][]cellOp
+
+// parseCellTransforms parses "COL=OP [OP...][;COL=OP...]". An operation is
+// swap_latlng or an arithmetic step, "*K", "/K", "+K" or "-K", applied to
+// every number of the cell; "9=*0.0000001" turns integers scaled by 1e7
+// back into degrees. There is nothing else: no variables, no functions, so
+// a value can't do more than the flag says.
+func parseCellTransforms(value string) (cellTransforms, error) {
+	t := cellTransforms{}
+	for _, part := range strings.Split(value, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		eq := strings.Index(part, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("%w: -transform-col %q, expected COL=OP", ErrBadInput, part)
+		}
+		col, err := strconv.Atoi(strings.TrimSpace(part[:eq]))
+		if err != nil || col < 0 {
+			return nil, fmt.Errorf("%w: -transform-col %q, column must be a non-negative index", ErrBadInput, part)
+		}
+
+		ops := strings.Fields(part[eq+1:])
+		if len(ops) == 0 {
+			return nil, fmt.Errorf("%w: -transform-col %q has no operation", ErrBadInput, part)
+		}
+		for _, op := range ops {
+			f, err := parseCellOp(op)
+			if err != nil {
+				return nil, err
+			}
+			t[col] = append(t[col], f)
+		}
+	}
+
+	if len(t) == 0 {
+		return nil, fmt.Errorf("%w: -transform-col %q has no operation", ErrBadInput, value)
+	}
+
+	return t, nil
+}
+
+func parseCellOp(op string) (cellOp, error) {
+	if op == SwapLatLng {
+		return func(nums []float64) []float64 {
+			if len(nums) == 2 {
+				nums[0], nums[1] = nums[1], nums[0]
+			}
+			return nums
+		}, nil
+	}
+
+	k, err := strconv.ParseFloat(op[1:], 64)
+	if err != nil || len(op) < 2 {
+		return nil, fmt.Errorf("%w: -transform-col operation %q, expected %s, *K, /K, +K or -K", ErrBadInput, op, SwapLatLng)
+	}
+
+	var f func(v float64) float64
+	switch op[0] {
+	case '*':
+		f = func(v float64) float64 { return v * k }
+	case '/':
+		if k == 0 {
+			return nil, fmt.Errorf("%w: -transform-col operation %q divides by zero", ErrBadInput, op)
+		}
+		f = func(v float64) float64 { return v / k }
+	case '+':
+		f = func(v float64) float64 { return v + k }
+	case '-':
+		f = func(v float64) float64 { return v - k }
+	default:
+		return nil, fmt.Errorf("%w: -transform-col operation %q, expected %s, *K, /K, +K or -K", ErrBadInput, op, SwapLatLng)
+	}
+
+	return func(nums []float64) []float64 {
+		for i := range nums {
+			nums[i] = f(nums[i])
+		}
+		return nums
+	}, nil
+}
+
+// apply rewrites the transformed cells of record in place. Empty and
+// missing cells are left alone; a waypoints cell is transformed stop by
+// stop.
+func (t cellTransforms) apply(record []string) error {
+	for col, ops := range t {
+		if col >= len(record) || strings.TrimSpace(record[col]) == "" {
+			continue
+		}
+
+		stops := strings.Split(record[col], WaypointSeparator)
+		for i, stop := range stops {
+			out, err := transformCell(stop, ops)
+			if err != nil {
+				return fmt.Errorf("-transform-col %d: %w", col, err)
+			}
+			stops[i] = out
+		}
+		record[col] = strings.Join(stops, WaypointSeparator)
+	}
+
+	return nil
+}
+
+// transformCell runs ops over the numbers of one pair or single value and
+// writes them back in the input's decimal convention.
+func transformCell(cell string, ops []cellOp) (string, error) {
+	fields := []string{cell}
+	if xy, err := splitPair(cell); err == nil {
+		fields = xy
+	}
+
+	nums := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := parseCoordinate(field)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not a number", ErrLatLong, strings.TrimSpace(field))
+		}
+		nums[i] = v
+	}
+
+	for _, op := range ops {
+		nums = op(nums)
+	}
+
+	out := make([]string, len(nums))
+	for i, v := range nums {
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if decimalComma {
+			s = strings.Replace(s, ".", ",", 1)
+		}
+		out[i] = s
+	}
+
+	if decimalComma {
+		return strings.Join(out, " "), nil
+	}
+	return strings.Join(out, ","), nil
+}
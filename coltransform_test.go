@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseCellTransforms(t *testing.T) {
+	tests := []struct {
+		value string
+		cols  map[int]int
+	}{
+		{value: "9=/10000000", cols: map[int]int{9: 1}},
+		{value: "9=swap_latlng *2; 12=+1;", cols: map[int]int{9: 2, 12: 1}},
+		{value: "9=+1;9=-1", cols: map[int]int{9: 2}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCellTransforms(tt.value)
+		if err != nil {
+			t.Errorf("parseCellTransforms(%q): %v", tt.value, err)
+			continue
+		}
+		if len(got) != len(tt.cols) {
+			t.Errorf("parseCellTransforms(%q) has %d columns, want %d", tt.value, len(got), len(tt.cols))
+		}
+		for col, n := range tt.cols {
+			if len(got[col]) != n {
+				t.Errorf("parseCellTransforms(%q) column %d has %d operations, want %d", tt.value, col, len(got[col]), n)
+			}
+		}
+	}
+}
+
+func TestParseCellTransformsErrors(t *testing.T) {
+	for _, value := range []string{
+		"",
+		";",
+		"9",
+		"x=*2",
+		"-1=*2",
+		"9=",
+		"9=*",
+		"9=*two",
+		"9=/0",
+		"9=%2",
+		"9=swap",
+		"9=os.Exit(1)",
+	} {
+		if _, err := parseCellTransforms(value); !errors.Is(err, ErrBadInput) {
+			t.Errorf("parseCellTransforms(%q) error = %v, want ErrBadInput", value, err)
+		}
+	}
+}
+
+func TestCellTransformsApply(t *testing.T) {
+	defer func(v bool) { decimalComma = v }(decimalComma)
+
+	tests := []struct {
+		name    string
+		value   string
+		comma   bool
+		record  []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "scaled integers",
+			value:  "0=/10000000",
+			record: []string{"-62000000,1068000000", "x"},
+			want:   []string{"-6.2,106.8", "x"},
+		},
+		{
+			name:   "swap then offset, in order",
+			value:  "1=swap_latlng +1",
+			record: []string{"a", "106.8,-6.2"},
+			want:   []string{"a", "-5.2,107.8"},
+		},
+		{
+			name:   "single value",
+			value:  "0=*2",
+			record: []string{"21"},
+			want:   []string{"42"},
+		},
+		{
+			name:   "waypoints stop by stop",
+			value:  "0=swap_latlng",
+			record: []string{"106.8,-6.2|106.7,-6.1"},
+			want:   []string{"-6.2,106.8|-6.1,106.7"},
+		},
+		{
+			name:   "empty and missing cells left alone",
+			value:  "0=*2;5=*2",
+			record: []string{" "},
+			want:   []string{" "},
+		},
+		{
+			name:   "comma decimals",
+			value:  "0=swap_latlng",
+			comma:  true,
+			record: []string{"106,8;-6,2"},
+			want:   []string{"-6,2 106,8"},
+		},
+		{
+			name:    "not a number",
+			value:   "0=*2",
+			record:  []string{"north,east"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		decimalComma = tt.comma
+		ct, err := parseCellTransforms(tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ct.apply(tt.record)
+		if tt.wantErr {
+			if !errors.Is(err, ErrLatLong) {
+				t.Errorf("%s: error = %v, want ErrLatLong", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || strings.Join(tt.record, "/") != strings.Join(tt.want, "/") {
+			t.Errorf("%s: record = %q, %v, want %q", tt.name, tt.record, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"time"
+
+	sm "github.com/flopp/go-staticmaps"
+)
+
+// ExitDeadline is the exit status after a run cut short by -deadline, as
+// timeout(1) uses.
+const ExitDeadline = 124
+
+// DeadlineGrace is how long after -deadline the partial output may take to
+// write before the process exits regardless.
+const DeadlineGrace = 10 * time.Second
+
+var ErrDeadline = errors.New("Deadline exceeded")
+
+var deadline time.Time
+
+// startDeadline makes the run stop reading and rendering once d has
+// passed, so the partial output gets written, and exits the process if
+// even that stalls beyond DeadlineGrace.
+func startDeadline(d time.Duration) {
+	deadline = time.Now().Add(d)
+
+	go func() {
+		time.Sleep(d + DeadlineGrace)
+		fmt.Println(fmt.Sprintf("\nError: %v, -deadline %s passed %s ago, aborting", ErrDeadline, d, DeadlineGrace))
+		removeSpills()
+		finishAudit(AuditDeadline, fmt.Errorf("%w, aborted after %s more", ErrDeadline, DeadlineGrace))
+		os.Exit(ExitDeadline)
+	}()
+}
+
+func deadlinePassed() bool {
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
+
+// renderBefore renders ctx, giving up with ErrDeadline at the deadline. The
+// abandoned render keeps running until the process exits; go-staticmaps
+// has no way to cancel tile fetches.
+func renderBefore(ctx *sm.Context) (image.Image, error) {
+	if deadline.IsZero() {
+		return ctx.Render()
+	}
+
+	type result struct {
+		img image.Image
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		img, err := ctx.Render()
+		done <- result{img, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.img, r.err
+	case <-time.After(time.Until(deadline)):
+		return nil, fmt.Errorf("%w while fetching tiles", ErrDeadline)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Decimal separators for -decimal-sep
+const (
+	DecimalPoint = "."
+	DecimalComma = ","
+)
+
+// decimalComma is set by -decimal-sep ",". A comma then can't split a
+// "lat,lng" cell, so the pair is split on a semicolon or whitespace
+// instead, e.g. "-6,2 106,8", and the CSV delimiter defaults to ";". It
+// applies to the input only; flag values keep decimal points.
+var decimalComma bool
+
+// splitPair splits a coordinate cell into its two numbers.
+func splitPair(latlong string) ([]string, error) {
+	if !decimalComma {
+		xy := strings.Split(latlong, ",")
+		if len(xy) != 2 {
+			return nil, fmt.Errorf("%w: expected 2 comma separated fields, got %d in %q", ErrLatLong, len(xy), latlong)
+		}
+		return xy, nil
+	}
+
+	xy := strings.FieldsFunc(latlong, func(r rune) bool { return r == ';' || unicode.IsSpace(r) })
+	if len(xy) != 2 {
+		return nil, fmt.Errorf("%w: -decimal-sep , expects 2 fields separated by \";\" or a space, got %d in %q", ErrLatLong, len(xy), latlong)
+	}
+
+	return xy, nil
+}
+
+// normalizeDecimal turns a comma decimal number into one strconv parses.
+func normalizeDecimal(value string) string {
+	if !decimalComma {
+		return value
+	}
+
+	return strings.Replace(value, ",", ".", 1)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetLatLongDecimalComma(t *testing.T) {
+	defer func(v bool) { decimalComma = v }(decimalComma)
+
+	tests := []struct {
+		in           string
+		comma        bool
+		wantX, wantY float64
+		wantErr      bool
+	}{
+		{in: "-6.2,106.8", wantX: -6.2, wantY: 106.8},
+		{in: "-6,2;106,8", comma: true, wantX: -6.2, wantY: 106.8},
+		{in: "-6,2 106,8", comma: true, wantX: -6.2, wantY: 106.8},
+		{in: " -6,2 ;  106 ", comma: true, wantX: -6.2, wantY: 106},
+		{in: "6,2°S;106,8°E", comma: true, wantX: -6.2, wantY: 106.8},
+		{in: "-6,2,106,8", comma: true, wantErr: true},
+		{in: ";", comma: true, wantErr: true},
+		{in: "-6,2;106,8", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		decimalComma = tt.comma
+		x, y, err := getLatLong(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("getLatLong(%q) comma %v = %v, %v, want an error", tt.in, tt.comma, x, y)
+			}
+			continue
+		}
+		if err != nil || x != tt.wantX || y != tt.wantY {
+			t.Errorf("getLatLong(%q) comma %v = %v, %v, %v, want %v, %v", tt.in, tt.comma, x, y, err, tt.wantX, tt.wantY)
+		}
+	}
+
+	decimalComma = true
+	if _, _, err := getLatLong("-6,2"); !errors.Is(err, ErrLatLong) {
+		t.Errorf("single field error = %v, want ErrLatLong", err)
+	}
+}
+
+func TestMarkLocationsDecimalComma(t *testing.T) {
+	defer func(v bool) { decimalComma = v }(decimalComma)
+	decimalComma = true
+
+	opts := testOptions(t)
+	opts.delimiter = ';'
+	opts.filename = filepath.Join(t.TempDir(), "in.csv")
+	content := "order_id;a;b;c;d;e;f;g;h;seller_coordinates;i;j;buyer_coordinates\n" +
+		"1;;;;;;;;;-6,38 106,88;;;-6,13 106,78\n" +
+		"2;;;;;;;;;\"-6,20;106,80\";;;\"-6,10;106,70\"\n"
+	if err := ioutil.WriteFile(opts.filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, sum, err := markLocations(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Routes != 2 {
+		t.Errorf("%d routes, want 2", sum.Routes)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang/geo/s2"
+)
+
+// Row statuses in the diagnostics output
+const (
+	StatusPlotted = "plotted"
+	StatusSkipped = "skipped"
+)
+
+// diagRow is one NDJSON line of -diag-out.
+type diagRow struct {
+	File   string       `json:"file"`
+	Row    int          `json:"row"`
+	ID     string       `json:"id,omitempty"`
+	Status string       `json:"status"`
+	Reason string       `json:"reason,omitempty"`
+	Src    *[2]float64  `json:"src,omitempty"`
+	Dst    *[2]float64  `json:"dst,omitempty"`
+	Stops  [][2]float64 `json:"stops,omitempty"`
+}
+
+func diagPoint(ll s2.LatLng) *[2]float64 {
+	return &[2]float64{ll.Lat.Degrees(), ll.Lng.Degrees()}
+}
+
+// diagnose writes a diagnostics line when -diag-out is set.
+func (p *plotter) diagnose(d diagRow) {
+	if p.opts.diag == nil {
+		return
+	}
+
+	d.File = p.opts.filename
+	d.ID = p.rowIDs[d.Row]
+	p.opts.diag.Encode(d)
+}
+
+// noteRowID remembers the row's -id-col value for its diagnostics.
+func (p *plotter) noteRowID(row int, record []string) {
+	col := p.opts.idCol
+	if col < 0 || col >= len(record) {
+		return
+	}
+
+	if id := strings.TrimSpace(record[col]); id != "" {
+		if p.rowIDs == nil {
+			p.rowIDs = map[int]string{}
+		}
+		p.rowIDs[row] = id
+	}
+}
+
+// rowLabel names a row in verbose messages, with its -id-col value when
+// it has one.
+func (p *plotter) rowLabel(row int) string {
+	if id, ok := p.rowIDs[row]; ok {
+		return fmt.Sprintf("Row %d (id %s)", row, id)
+	}
+
+	return fmt.Sprintf("Row %d", row)
+}
+
+// routeDiag describes a route, including whichever ends parsed even when
+// the other failed.
+func routeDiag(rt route, err error) diagRow {
+	d := diagRow{Row: rt.Row, Status: StatusPlotted}
+
+	var srcErr, dstErr error
+	var re *routeError
+	if errors.As(err, &re) {
+		srcErr, dstErr = re.Src, re.Dst
+	} else if err != nil {
+		srcErr, dstErr = err, err
+	}
+
+	if srcErr == nil {
+		d.Src = diagPoint(rt.Src)
+	}
+	if dstErr == nil {
+		d.Dst = diagPoint(rt.Dst)
+	}
+	if err != nil {
+		d.Status = StatusSkipped
+		d.Reason = rowCause(err).Error()
+	}
+
+	return d
+}
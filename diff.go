@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s2"
+)
+
+// diffCommon colors the points diff mode finds in both files; added and
+// removed points use the theme's source and destination colors.
+var diffCommon = color.RGBA{0x80, 0x80, 0x80, 0xff}
+
+// pointIndex finds points within a radius using a grid of cells about
+// the radius wide.
+type pointIndex struct {
+	cellDeg float64
+	cells   map[[2]int][]int
+	points  []s2.LatLng
+	used    []bool
+}
+
+func newPointIndex(points []s2.LatLng, radiusMeters float64) *pointIndex {
+	idx := &pointIndex{
+		cellDeg: math.Max(radiusMeters/EarthRadiusMeters*180/math.Pi, 1e-9),
+		cells:   map[[2]int][]int{},
+		points:  points,
+		used:    make([]bool, len(points)),
+	}
+	for i, p := range points {
+		c := idx.cell(p)
+		idx.cells[c] = append(idx.cells[c], i)
+	}
+
+	return idx
+}
+
+func (idx *pointIndex) cell(p s2.LatLng) [2]int {
+	return [2]int{int(math.Floor(p.Lat.Degrees() / idx.cellDeg)), int(math.Floor(p.Lng.Degrees() / idx.cellDeg))}
+}
+
+// match marks and returns the nearest unused point within radiusMeters
+// of p.
+func (idx *pointIndex) match(p s2.LatLng, radiusMeters float64) bool {
+	c := idx.cell(p)
+
+	// a degree of longitude shrinks towards the poles, so look further
+	span := int(math.Ceil(1 / math.Max(math.Cos(p.Lat.Radians()), 1e-6)))
+	if span > 360 {
+		span = 360
+	}
+
+	best, bestDist := -1, radiusMeters
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -span; dx <= span; dx++ {
+			for _, i := range idx.cells[[2]int{c[0] + dy, c[1] + dx}] {
+				if idx.used[i] {
+					continue
+				}
+				if d := distanceMeters(p, idx.points[i], DistanceSpherical); d <= bestDist {
+					best, bestDist = i, d
+				}
+			}
+		}
+	}
+
+	if best < 0 {
+		return false
+	}
+	idx.used[best] = true
+	return true
+}
+
+// diffLayer compares the plotted points of -diff-base (A) with the
+// current layer (B). Points matched within the -precision tolerance are
+// drawn gray,
+// points only in A as removed and points only in B as added.
+func diffLayer(lyr *layer, opts options) (*layer, error) {
+	baseOpts := opts
+	baseOpts.filename = opts.diffBase
+	baseOpts.mode = "plot"
+	baseOpts.diag = nil
+	baseOpts.errorRows = nil
+	// the audit hashes the input B; reading A through the same hasher
+	// would reset it and record A's hash for B
+	baseOpts.hasher = nil
+	base, _, err := markLocations(baseOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := markerPositions(base)
+	tolerance := opts.precision.tolerance()
+	idx := newPointIndex(removed, tolerance)
+
+	out := &layer{}
+	added, common := 0, 0
+	for _, p := range markerPositions(lyr) {
+		c := opts.theme.Source
+		if idx.match(p, tolerance) {
+			c = diffCommon
+			common++
+		} else {
+			added++
+		}
+		out.addMarker(sm.NewMarker(p, c, opts.markerSize))
+	}
+
+	for i, p := range removed {
+		if !idx.used[i] {
+			out.addMarker(sm.NewMarker(p, opts.theme.Destination, opts.markerSize))
+		}
+	}
+
+	fmt.Println(fmt.Sprintf("Diff %s -> %s: added %d, removed %d, unchanged %d",
+		opts.diffBase, opts.filename, added, len(removed)-common, common))
+
+	return out, nil
+}
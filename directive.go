@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DirectivePrefix starts the optional first line configuring a run, e.g.
+// "#courierinfo: src=9 dst=12 bbox=-11,95,6,141".
+const DirectivePrefix = "#courierinfo:"
+
+// directiveAliases maps short directive keys to flag names; any other key
+// must be a flag name itself.
+var directiveAliases = map[string]string{
+	"src": "src-col",
+	"dst": "dst-col",
+}
+
+// directiveAllowed lists the flags a directive may set: how the rows are
+// read and drawn. Flags naming other files, URLs or outputs, and the
+// safety limits, stay with the command line, so an input can't reach
+// beyond itself.
+var directiveAllowed = map[string]bool{
+	"arc-curvature":        true,
+	"arc-style":            true,
+	"background":           true,
+	"bbox":                 true,
+	"buffer-alpha":         true,
+	"buffer-km":            true,
+	"center":               true,
+	"centroid":             true,
+	"centroid-label":       true,
+	"clip-to-view":         true,
+	"color-by-distance":    true,
+	"coord-type":           true,
+	"coord-unit":           true,
+	"dash":                 true,
+	"decimal-sep":          true,
+	"delimiter":            true,
+	"distance-max":         true,
+	"distance-min":         true,
+	"distance-model":       true,
+	"draw-ids":             true,
+	"dst-col":              true,
+	"dst-color":            true,
+	"ellipse-sigma":        true,
+	"fixed-zoom":           true,
+	"flip-x":               true,
+	"flip-y":               true,
+	"focus-percentile":     true,
+	"freq-size":            true,
+	"geodesic":             true,
+	"glyph-col":            true,
+	"grayscale":            true,
+	"group-col":            true,
+	"group-colors":         true,
+	"header-rows":          true,
+	"heatmap-cell":         true,
+	"hop-labels":           true,
+	"id-col":               true,
+	"jitter":               true,
+	"label-col":            true,
+	"lazy-quotes":          true,
+	"line-cap":             true,
+	"line-color":           true,
+	"line-join":            true,
+	"marker-outline":       true,
+	"marker-outline-width": true,
+	"marker-size":          true,
+	"marker-style":         true,
+	"midpoints":            true,
+	"no-bounds":            true,
+	"no-order-detect":      true,
+	"north-arrow":          true,
+	"origin":               true,
+	"region-col":           true,
+	"role-col":             true,
+	"role-style":           true,
+	"role-values":          true,
+	"seed":                 true,
+	"sentinels":            true,
+	"size-col":             true,
+	"size-max":             true,
+	"size-min":             true,
+	"src-col":              true,
+	"src-color":            true,
+	"stats-box":            true,
+	"std-ellipse":          true,
+	"theme":                true,
+	"time-col":             true,
+	"transform":            true,
+	"transform-col":        true,
+	"transport-col":        true,
+	"transport-styles":     true,
+	"waypoints-col":        true,
+	"width-by-distance":    true,
+	"width-max":            true,
+	"width-min":            true,
+	"wrap":                 true,
+}
+
+// applyDirective reads the first line of filename and applies its settings
+// to every flag not given on the command line, so flags always win. The
+// input stays open for the read to take over, so a URL isn't fetched twice
+// and standard input isn't consumed.
+func applyDirective(filename string, opts options) error {
+	file, err := openSource(filename, opts)
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(file)
+	line, err := br.ReadString('\n')
+	keepOpen(filename, io.MultiReader(strings.NewReader(line), br), file)
+	if err != nil && line == "" {
+		return nil
+	}
+
+	settings, ok, err := parseDirective(line)
+	if !ok || err != nil {
+		return err
+	}
+
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	for _, kv := range settings {
+		if set[kv[0]] {
+			continue
+		}
+		if err := flag.Set(kv[0], kv[1]); err != nil {
+			return fmt.Errorf("%w: directive %s=%s: %v", ErrBadInput, kv[0], kv[1], err)
+		}
+		directiveFlags[kv[0]] = true
+	}
+
+	return nil
+}
+
+// parseDirective returns the flag name/value pairs of a directive line and
+// whether the line is a directive at all.
+func parseDirective(line string) ([][2]string, bool, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, DirectivePrefix) {
+		return nil, false, nil
+	}
+
+	var settings [][2]string
+	for _, field := range strings.Fields(strings.TrimPrefix(line, DirectivePrefix)) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return nil, true, fmt.Errorf("%w: directive field %q, expected key=value", ErrBadInput, field)
+		}
+
+		name := kv[0]
+		if alias, ok := directiveAliases[name]; ok {
+			name = alias
+		}
+		if !directiveAllowed[name] {
+			if flag.Lookup(name) == nil {
+				return nil, true, fmt.Errorf("%w: unknown directive key %q", ErrBadInput, kv[0])
+			}
+			return nil, true, fmt.Errorf("%w: directive key %q can only be set on the command line", ErrBadInput, kv[0])
+		}
+
+		settings = append(settings, [2]string{name, kv[1]})
+	}
+
+	return settings, true, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestParseDirective(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    string
+		ok      bool
+		wantErr bool
+	}{
+		{line: "order_id,seller", ok: false},
+		{line: "#courierinfo: src=9 dst=12 bbox=-11,95,6,141", want: "[[src-col 9] [dst-col 12] [bbox -11,95,6,141]]", ok: true},
+		{line: "  #courierinfo: theme=dark marker-style=circle\r", want: "[[theme dark] [marker-style circle]]", ok: true},
+		{line: "#courierinfo: src", ok: true, wantErr: true},
+		{line: "#courierinfo: nosuchflag=1", ok: true, wantErr: true},
+		{line: "#courierinfo: outdir=/etc", ok: true, wantErr: true},
+		{line: "#courierinfo: o=../../img.png", ok: true, wantErr: true},
+		{line: "#courierinfo: tiles=evil", ok: true, wantErr: true},
+		{line: "#courierinfo: geocode=http://example.com", ok: true, wantErr: true},
+		{line: "#courierinfo: max-markers=0", ok: true, wantErr: true},
+		{line: "#courierinfo: audit-log=/dev/null", ok: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		settings, ok, err := parseDirective(tt.line)
+		if ok != tt.ok {
+			t.Errorf("parseDirective(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+		}
+		if tt.wantErr {
+			if !errors.Is(err, ErrBadInput) {
+				t.Errorf("parseDirective(%q) error = %v, want ErrBadInput", tt.line, err)
+			}
+			continue
+		}
+		if err != nil || (tt.want != "" && fmt.Sprint(settings) != tt.want) {
+			t.Errorf("parseDirective(%q) = %v, %v, want %s", tt.line, settings, err, tt.want)
+		}
+	}
+}
+
+// countingOpener is a mapOpener counting how often each input is opened.
+type countingOpener struct {
+	mapOpener
+	opens int
+}
+
+func (o *countingOpener) Open(src string) (io.ReadCloser, error) {
+	o.opens++
+	return o.mapOpener.Open(src)
+}
+
+func TestApplyDirectiveKeepsInputOpen(t *testing.T) {
+	// flags aren't registered in tests, so the input has no directive
+	const content = "# exported today\n" + sampleCSV
+	opener := &countingOpener{mapOpener: mapOpener{"mem://in": content}}
+	opts := testOptions(t)
+	opts.opener = opener
+
+	if err := applyDirective("mem://in", opts); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readSource(t, "mem://in", opts)
+	if err != nil || got != content {
+		t.Errorf("read after the directive %q, %v, want the whole input", got, err)
+	}
+	if opener.opens != 1 {
+		t.Errorf("input opened %d times, want 1", opener.opens)
+	}
+
+	// the parked stream is handed out once, later reads open the input
+	rc, err := openSource("mem://in", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, _ := ioutil.ReadAll(rc); !strings.HasPrefix(string(b), "# exported") || opener.opens != 2 {
+		t.Errorf("second read %q after %d opens", b, opener.opens)
+	}
+}
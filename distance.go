@@ -0,0 +1,101 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// Distance models
+const (
+	DistanceSpherical = "spherical"
+	DistanceEllipsoid = "ellipsoid"
+
+	// EarthRadiusMeters is the mean Earth radius used by the spherical model.
+	EarthRadiusMeters = 6371008.8
+)
+
+// WGS84 ellipsoid parameters
+const (
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+	wgs84B = wgs84A * (1 - wgs84F)
+)
+
+// distanceMeters returns the distance between a and b in meters using the
+// given model. Unknown models fall back to spherical.
+func distanceMeters(a, b s2.LatLng, model string) float64 {
+	if model == DistanceEllipsoid {
+		if d, ok := vincentyDistance(a, b); ok {
+			return d
+		}
+	}
+
+	return a.Distance(b).Radians() * EarthRadiusMeters
+}
+
+// vincentyDistance implements Vincenty's inverse formula on the WGS84
+// ellipsoid. It reports false when the iteration fails to converge, which
+// happens for nearly antipodal points.
+func vincentyDistance(a, b s2.LatLng) (float64, bool) {
+	L := b.Lng.Radians() - a.Lng.Radians()
+	U1 := math.Atan((1 - wgs84F) * math.Tan(a.Lat.Radians()))
+	U2 := math.Atan((1 - wgs84F) * math.Tan(b.Lat.Radians()))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	lambda := L
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM float64
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma = math.Sqrt((cosU2*sinLambda)*(cosU2*sinLambda) +
+			(cosU1*sinU2-sinU1*cosU2*cosLambda)*(cosU1*sinU2-sinU1*cosU2*cosLambda))
+		if sinSigma == 0 {
+			return 0, true // coincident points
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cosSqAlpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+		C := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+		prev := lambda
+		lambda = L + (1-C)*wgs84F*sinAlpha*
+			(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) < 1e-12 {
+			uSq := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+			A := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+			B := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+			deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+				B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+			return wgs84B * A * (sigma - deltaSigma), true
+		}
+	}
+
+	return 0, false
+}
+
+// distanceColor maps a distance in meters onto a green (short) to red (long)
+// gradient spanning minKm..maxKm. Distances outside the range are clamped.
+func distanceColor(meters, minKm, maxKm float64) color.RGBA {
+	t := 0.0
+	if maxKm > minKm {
+		t = (meters/1000 - minKm) / (maxKm - minKm)
+	}
+
+	return gradientColor(t)
+}
+
+// gradientColor returns the green→yellow→red color at t in [0, 1].
+func gradientColor(t float64) color.RGBA {
+	t = math.Max(0, math.Min(1, t))
+	if t < 0.5 {
+		return color.RGBA{uint8(255 * t * 2), 0xff, 0x00, 0xff}
+	}
+
+	return color.RGBA{0xff, uint8(255 * (1 - t) * 2), 0x00, 0xff}
+}
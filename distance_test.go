@@ -0,0 +1,88 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestDistanceMeters(t *testing.T) {
+	flinders := s2.LatLngFromDegrees(-(37 + 57/60.0 + 3.72030/3600), 144+25/60.0+29.52440/3600)
+	buninyong := s2.LatLngFromDegrees(-(37 + 39/60.0 + 10.15610/3600), 143+55/60.0+35.38390/3600)
+
+	tests := []struct {
+		name  string
+		a, b  s2.LatLng
+		model string
+		want  float64
+		tol   float64
+	}{
+		{"spherical degree", s2.LatLngFromDegrees(0, 0), s2.LatLngFromDegrees(0, 1), DistanceSpherical, 111195.08, 0.01},
+		{"ellipsoid equator degree", s2.LatLngFromDegrees(0, 0), s2.LatLngFromDegrees(0, 1), DistanceEllipsoid, 111319.49, 0.01},
+		{"ellipsoid Flinders Peak to Buninyong", flinders, buninyong, DistanceEllipsoid, 54972.271, 0.001},
+		{"ellipsoid coincident", flinders, flinders, DistanceEllipsoid, 0, 0},
+		{"unknown model is spherical", s2.LatLngFromDegrees(0, 0), s2.LatLngFromDegrees(0, 1), "flat", 111195.08, 0.01},
+	}
+
+	for _, tt := range tests {
+		if got := distanceMeters(tt.a, tt.b, tt.model); math.Abs(got-tt.want) > tt.tol {
+			t.Errorf("%s: distanceMeters = %.4f, want %.4f", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestDistanceModels compares both models on city pairs. The ellipsoid is
+// wider at the equator than the sphere and flatter towards the poles, so
+// it makes east-west routes near the equator longer and north-south ones
+// shorter.
+func TestDistanceModels(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b       s2.LatLng
+		spherical  float64
+		ellipsoid  float64
+		difference float64 // ellipsoid minus spherical
+	}{
+		{name: "Jakarta to Jayapura, east-west", a: s2.LatLngFromDegrees(-6.2088, 106.8456), b: s2.LatLngFromDegrees(-2.5337, 140.7181),
+			spherical: 3776684.907, ellipsoid: 3780694.556, difference: 4009.649},
+		{name: "Manado to Kupang, north-south", a: s2.LatLngFromDegrees(1.4748, 124.8421), b: s2.LatLngFromDegrees(-10.1772, 123.6070),
+			spherical: 1302836.615, ellipsoid: 1295777.553, difference: -7059.062},
+		// along a meridian the ellipsoid length is the meridian arc
+		{name: "6N to 11S along 106.8E", a: s2.LatLngFromDegrees(6, 106.8), b: s2.LatLngFromDegrees(-11, 106.8),
+			spherical: 1890316.364, ellipsoid: 1879935.975, difference: -10380.389},
+	}
+
+	for _, tt := range tests {
+		sph := distanceMeters(tt.a, tt.b, DistanceSpherical)
+		ell := distanceMeters(tt.a, tt.b, DistanceEllipsoid)
+		if math.Abs(sph-tt.spherical) > 0.01 {
+			t.Errorf("%s: spherical = %.3f, want %.3f", tt.name, sph, tt.spherical)
+		}
+		if math.Abs(ell-tt.ellipsoid) > 0.01 {
+			t.Errorf("%s: ellipsoid = %.3f, want %.3f", tt.name, ell, tt.ellipsoid)
+		}
+		if d := ell - sph; math.Abs(d-tt.difference) > 0.02 {
+			t.Errorf("%s: ellipsoid - spherical = %.3f, want %.3f", tt.name, d, tt.difference)
+		}
+	}
+}
+
+func TestDistanceColor(t *testing.T) {
+	tests := []struct {
+		meters float64
+		want   color.RGBA
+	}{
+		{0, color.RGBA{0x00, 0xff, 0x00, 0xff}},
+		{50000, color.RGBA{0xff, 0xff, 0x00, 0xff}},
+		{100000, color.RGBA{0xff, 0x00, 0x00, 0xff}},
+		{500000, color.RGBA{0xff, 0x00, 0x00, 0xff}},
+	}
+
+	for _, tt := range tests {
+		if got := distanceColor(tt.meters, 0, 100); got != tt.want {
+			t.Errorf("distanceColor(%v, 0, 100) = %v, want %v", tt.meters, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/fogleman/gg"
+	"github.com/golang/geo/s2"
+)
+
+// ellipseSteps is the number of points an -std-ellipse outline is drawn
+// through.
+const ellipseSteps = 72
+
+// stdEllipse is the standard deviational ellipse of the sources: their
+// mean center and the spread along the axes of greatest and least
+// dispersion, times -ellipse-sigma.
+type stdEllipse struct {
+	Center       s2.LatLng
+	Major, Minor float64 // semi-axes, km
+	Rotation     float64 // of the major axis, degrees clockwise from north
+	Sigma        float64
+	Points       int
+}
+
+// standardEllipse computes the ellipse of points. Every point weighs the
+// same, so each location weighs as many times as routes start there. The
+// spread is measured in km on a plane tangent at the mean center, which
+// holds for data spanning a region, not a continent.
+func standardEllipse(points []s2.LatLng, sigma float64) (*stdEllipse, bool) {
+	if len(points) < 3 {
+		return nil, false
+	}
+	c, ok := centroid(points, CentroidMean)
+	if !ok {
+		return nil, false
+	}
+
+	var sxx, syy, sxy float64
+	for _, p := range points {
+		x, y := tangentKm(c, p)
+		sxx += x * x
+		syy += y * y
+		sxy += x * y
+	}
+	n := float64(len(points))
+	sxx, syy, sxy = sxx/n, syy/n, sxy/n
+
+	// eigenvalues of the covariance matrix are the variances along the
+	// axes, the angle that of the major axis from east, counterclockwise
+	mid := (sxx + syy) / 2
+	d := math.Sqrt((sxx-syy)*(sxx-syy)/4 + sxy*sxy)
+	angle := 0.5 * math.Atan2(2*sxy, sxx-syy)
+
+	rotation := math.Mod(90-angle*180/math.Pi+180, 180)
+	return &stdEllipse{
+		Center:   c,
+		Major:    sigma * math.Sqrt(mid+d),
+		Minor:    sigma * math.Sqrt(math.Max(mid-d, 0)),
+		Rotation: rotation,
+		Sigma:    sigma,
+		Points:   len(points),
+	}, true
+}
+
+// tangentKm places p on the plane tangent at c, x east and y north, in
+// km.
+func tangentKm(c, p s2.LatLng) (float64, float64) {
+	dLng := math.Remainder(p.Lng.Degrees()-c.Lng.Degrees(), 360) * math.Pi / 180
+	dLat := (p.Lat.Degrees() - c.Lat.Degrees()) * math.Pi / 180
+	r := EarthRadiusMeters / 1000
+	return r * dLng * math.Cos(c.Lat.Radians()), r * dLat
+}
+
+// outline returns the ellipse as positions on the globe.
+func (e *stdEllipse) outline() []s2.LatLng {
+	r := EarthRadiusMeters / 1000
+	theta := (90 - e.Rotation) * math.Pi / 180
+	cosLat := math.Max(math.Cos(e.Center.Lat.Radians()), 1e-6)
+
+	points := make([]s2.LatLng, 0, ellipseSteps+1)
+	for i := 0; i <= ellipseSteps; i++ {
+		t := 2 * math.Pi * float64(i) / ellipseSteps
+		u, v := e.Major*math.Cos(t), e.Minor*math.Sin(t)
+		x := u*math.Cos(theta) - v*math.Sin(theta)
+		y := u*math.Sin(theta) + v*math.Cos(theta)
+		points = append(points, s2.LatLngFromDegrees(
+			e.Center.Lat.Degrees()+y/r*180/math.Pi,
+			e.Center.Lng.Degrees()+x/(r*cosLat)*180/math.Pi))
+	}
+
+	return points
+}
+
+func (e *stdEllipse) String() string {
+	return fmt.Sprintf("center %f,%f, semi-axes %.3f km and %.3f km, rotation %.1f° from north (%g sigma, %d sources)",
+		e.Center.Lat.Degrees(), e.Center.Lng.Degrees(), e.Major, e.Minor, e.Rotation, e.Sigma, e.Points)
+}
+
+// drawEllipse outlines the ellipse over a light fill and crosses its
+// center.
+func drawEllipse(img image.Image, e *stdEllipse, vp Viewport, t theme) image.Image {
+	dc := gg.NewContextForImage(img)
+
+	for i, ll := range e.outline() {
+		x, y := vp.Project(ll)
+		if i == 0 {
+			dc.MoveTo(x, y)
+		} else {
+			dc.LineTo(x, y)
+		}
+	}
+	dc.ClosePath()
+	dc.SetColor(color.RGBA{0x80, 0x00, 0x80, 0x30})
+	dc.FillPreserve()
+	dc.SetColor(color.RGBA{0x80, 0x00, 0x80, 0xff})
+	dc.SetLineWidth(2 * pixelScale)
+	dc.Stroke()
+
+	x, y := vp.Project(e.Center)
+	arm := 5 * pixelScale
+	dc.SetColor(t.Text)
+	dc.SetLineWidth(1.5 * pixelScale)
+	dc.DrawLine(x-arm, y, x+arm, y)
+	dc.DrawLine(x, y-arm, x, y+arm)
+	dc.Stroke()
+
+	return dc.Image()
+}
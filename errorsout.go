@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// ReasonColumn names the column -errors-out appends with each row's skip
+// reason.
+const ReasonColumn = "skip_reason"
+
+// errorRows collects the skipped rows of every input for -errors-out, as
+// they were read, before -transform-col.
+type errorRows struct {
+	delimiter rune
+
+	// header rows of the first input, written once
+	headerFile string
+	header     [][]string
+
+	rows    [][]string
+	reasons []string
+}
+
+func (e *errorRows) noteHeader(file string, record []string) {
+	if e.headerFile == "" {
+		e.headerFile = file
+	}
+	if file == e.headerFile {
+		e.header = append(e.header, record)
+	}
+}
+
+// add records a skipped row; record is nil when the line wasn't valid CSV.
+func (e *errorRows) add(record []string, reason string) {
+	e.rows = append(e.rows, record)
+	e.reasons = append(e.reasons, reason)
+}
+
+// write writes the header and the skipped rows in the input's delimiter.
+// Rows are padded to the widest one, ragged input included, so the reason
+// lands in the same last column of every row and the file stays
+// rectangular for spreadsheets; the fields themselves are verbatim. The
+// original columns keep their indexes, so the fixed file can be re-run
+// with the same flags.
+func (e *errorRows) write(w io.Writer) error {
+	width := 0
+	for _, rows := range [][][]string{e.header, e.rows} {
+		for _, r := range rows {
+			if len(r) > width {
+				width = len(r)
+			}
+		}
+	}
+
+	pad := func(r []string, last string) []string {
+		out := make([]string, width+1)
+		copy(out, r)
+		out[width] = last
+		return out
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = e.delimiter
+	for i, r := range e.header {
+		last := ""
+		if i == len(e.header)-1 {
+			last = ReasonColumn
+		}
+		if err := cw.Write(pad(r, last)); err != nil {
+			return err
+		}
+	}
+	for i, r := range e.rows {
+		if err := cw.Write(pad(r, e.reasons[i])); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Sources of a flag value for -explain
+const (
+	SourceFlag      = "flag"
+	SourceDirective = "directive"
+	SourceDefault   = "default"
+)
+
+// directiveFlags are the flags the input's #courierinfo: line set.
+var directiveFlags = map[string]bool{}
+
+// explainFlag is the -explain value: text when given alone, or
+// -explain=json.
+type explainFlag string
+
+func (e *explainFlag) String() string { return string(*e) }
+
+func (e *explainFlag) Set(value string) error {
+	switch value {
+	case "true", "text":
+		*e = "text"
+	case "false":
+		*e = ""
+	case "json":
+		*e = "json"
+	default:
+		return fmt.Errorf("expected text or json, got %q", value)
+	}
+
+	return nil
+}
+
+func (e *explainFlag) IsBoolFlag() bool { return true }
+
+// setting is one flag in the effective configuration.
+type setting struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Default string `json:"default"`
+	Source  string `json:"source"`
+}
+
+// effectiveSettings lists every flag with its value and where the value
+// came from. Command line flags win over the directive, which wins over
+// the defaults.
+func effectiveSettings() []setting {
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var settings []setting
+	flag.VisitAll(func(f *flag.Flag) {
+		source := SourceDefault
+		if directiveFlags[f.Name] {
+			source = SourceDirective
+		} else if given[f.Name] {
+			source = SourceFlag
+		}
+		settings = append(settings, setting{Name: f.Name, Value: f.Value.String(), Default: f.DefValue, Source: source})
+	})
+
+	return settings
+}
+
+// writeExplain writes the effective configuration as JSON or an aligned
+// table, the flags not left at their default first.
+func writeExplain(w io.Writer, format string) error {
+	settings := effectiveSettings()
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(settings)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FLAG\tVALUE\tSOURCE")
+	for _, changed := range []bool{true, false} {
+		for _, s := range settings {
+			if (s.Source != SourceDefault) == changed {
+				fmt.Fprintf(tw, "-%s\t%q\t%s\n", s.Name, s.Value, s.Source)
+			}
+		}
+	}
+
+	return tw.Flush()
+}
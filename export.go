@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// ErrNoParquet is returned by parquet mode in builds without the writer.
+var ErrNoParquet = fmt.Errorf("%w: parquet mode needs a build with -tags parquet", ErrBadInput)
+
+// exportRow is a plotted route as parquet mode writes it: the validated
+// coordinates in degrees, the distance under -distance-model and the
+// -export-cols cells by column name.
+type exportRow struct {
+	Row        int64
+	SrcLat     float64
+	SrcLng     float64
+	DstLat     float64
+	DstLng     float64
+	DistanceKm float64
+	Columns    map[string]string
+}
+
+// exportRows turns the plotted routes into rows, in input order. Columns
+// are named by the header, or "col N" without one.
+func exportRows(lyr *layer, opts options) []exportRow {
+	rows := make([]exportRow, 0, len(lyr.routes))
+	for _, rt := range lyr.routes {
+		r := exportRow{
+			Row:        int64(rt.Row),
+			SrcLat:     rt.Src.Lat.Degrees(),
+			SrcLng:     rt.Src.Lng.Degrees(),
+			DstLat:     rt.Dst.Lat.Degrees(),
+			DstLng:     rt.Dst.Lng.Degrees(),
+			DistanceKm: distanceMeters(rt.Src, rt.Dst, opts.distanceModel) / 1000,
+			Columns:    map[string]string{},
+		}
+
+		for _, col := range opts.exportCols {
+			if col < 0 || col >= len(rt.Record) {
+				continue
+			}
+
+			name := fmt.Sprintf("col %d", col)
+			if col < len(lyr.header) && lyr.header[col] != "" {
+				name = lyr.header[col]
+			}
+			r.Columns[name] = rt.Record[col]
+		}
+
+		rows = append(rows, r)
+	}
+
+	return rows
+}
+
+// writeParquet writes the plotted routes as a Parquet file.
+func writeParquet(w io.Writer, lyr *layer, opts options) error {
+	if len(lyr.routes) == 0 {
+		return ErrTooFewRows
+	}
+
+	return encodeParquet(w, exportRows(lyr, opts))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/bmishra/courierInfo/mapview"
+)
+
+// extent describes the area covered by the plotted data and the framing a
+// render would use for it.
+type extent struct {
+	MinLat    float64 `json:"min_lat"`
+	MinLng    float64 `json:"min_lng"`
+	MaxLat    float64 `json:"max_lat"`
+	MaxLng    float64 `json:"max_lng"`
+	CenterLat float64 `json:"center_lat"`
+	CenterLng float64 `json:"center_lng"`
+	Zoom      int     `json:"zoom"`
+}
+
+// writeExtent writes the extent of the layer as JSON or plain text.
+func writeExtent(w io.Writer, lyr *layer, format string) error {
+	if lyr.empty() {
+		return ErrTooFewRows
+	}
+
+	b := lyr.bounds()
+	vp := mapview.FitViewport(b, MapWidth, MapHeight, lyr.margin())
+	e := extent{
+		MinLat:    b.Lo().Lat.Degrees(),
+		MinLng:    b.Lo().Lng.Degrees(),
+		MaxLat:    b.Hi().Lat.Degrees(),
+		MaxLng:    b.Hi().Lng.Degrees(),
+		CenterLat: vp.Center.Lat.Degrees(),
+		CenterLng: vp.Center.Lng.Degrees(),
+		Zoom:      vp.Zoom,
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(e)
+	}
+
+	_, err := fmt.Fprintf(w, "Extent: %f,%f %f,%f, center: %f,%f, zoom: %d\n",
+		e.MinLat, e.MinLng, e.MaxLat, e.MaxLng, e.CenterLat, e.CenterLng, e.Zoom)
+	return err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"path/filepath"
+	"sort"
+)
+
+// noteFile returns the color of an input's routes, adding it to the legend
+// the first time it is seen.
+func (p *plotter) noteFile(file string) color.RGBA {
+	c := fileColor(p.opts.fileColors, file)
+	if !p.filesSeen[file] {
+		if p.filesSeen == nil {
+			p.filesSeen = map[string]bool{}
+		}
+		p.filesSeen[file] = true
+		p.fileLegend = append(p.fileLegend, legendEntry{Label: sourceBaseName(file), Color: c})
+	}
+
+	return c
+}
+
+// fileColor returns the -file-colors color of an input, matched by the
+// path as given or by its base name, or a color hashed from the base name
+// when it isn't mapped.
+func fileColor(mapping map[string]color.RGBA, file string) color.RGBA {
+	if c, ok := mapping[file]; ok {
+		return c
+	}
+	if c, ok := mapping[filepath.Base(file)]; ok {
+		return c
+	}
+
+	return paletteColor(filepath.Base(file))
+}
+
+// warnUnmatchedFileColors warns about -file-colors entries naming none of
+// the inputs, most likely a typo.
+func warnUnmatchedFileColors(mapping map[string]color.RGBA, files []string) {
+	var names []string
+	for name := range mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		matched := false
+		for _, file := range files {
+			if name == file || name == filepath.Base(file) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fmt.Println(fmt.Sprintf("Warning: -file-colors %q matches no input", name))
+		}
+	}
+}
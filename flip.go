@@ -0,0 +1,36 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+)
+
+// flipImage mirrors img horizontally and/or vertically. It is a
+// diagnostic aid for checking orientation, so the sidecar viewport still
+// describes the unflipped map.
+func flipImage(img image.Image, flipX, flipY bool) image.Image {
+	if !flipX && !flipY {
+		return img
+	}
+
+	b := img.Bounds()
+	src := image.NewRGBA(b)
+	draw.Draw(src, b, img, b.Min, draw.Src)
+
+	dst := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		sy := y
+		if flipY {
+			sy = b.Max.Y - 1 - (y - b.Min.Y)
+		}
+		for x := b.Min.X; x < b.Max.X; x++ {
+			sx := x
+			if flipX {
+				sx = b.Max.X - 1 - (x - b.Min.X)
+			}
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s2"
+)
+
+// flowEdge is every route of flows mode between one source and
+// destination, paired within -precision.
+type flowEdge struct {
+	Src, Dst s2.LatLng
+	Weight   int
+	Row      int      // of the first route
+	Record   []string // of the first route
+}
+
+// flowStep is one line of the flows mode width legend.
+type flowStep struct {
+	Weight int
+	Width  float64
+	Color  color.NRGBA
+}
+
+// flowStyle scales an edge's line from -width-min and faint at weight 1 to
+// -width-max and opaque at the heaviest edge. c is an opaque theme color.
+func flowStyle(weight, maxWeight int, c color.RGBA, opts options) (float64, color.NRGBA) {
+	t := 1.0
+	if maxWeight > 1 {
+		t = float64(weight-1) / float64(maxWeight-1)
+	}
+
+	alpha := uint8(math.Round(0x50 + t*(0xff-0x50)))
+	return opts.widthMin + t*(opts.widthMax-opts.widthMin), color.NRGBA{c.R, c.G, c.B, alpha}
+}
+
+// flowEdges collapses routes with the same ends at prec into weighted
+// edges, lightest first so the heavy ones draw on top.
+func flowEdges(routes []route, prec precision) []flowEdge {
+	type key struct{ srcLat, srcLng, dstLat, dstLng float64 }
+
+	var edges []flowEdge
+	index := map[key]int{}
+	for _, rt := range routes {
+		srcLat, srcLng := prec.round(rt.Src)
+		dstLat, dstLng := prec.round(rt.Dst)
+		k := key{srcLat, srcLng, dstLat, dstLng}
+
+		i, ok := index[k]
+		if !ok {
+			i = len(edges)
+			index[k] = i
+			edges = append(edges, flowEdge{Src: rt.Src, Dst: rt.Dst, Row: rt.Row, Record: rt.Record})
+		}
+		edges[i].Weight++
+	}
+
+	sort.SliceStable(edges, func(i, j int) bool { return edges[i].Weight < edges[j].Weight })
+	return edges
+}
+
+// addFlows draws flows mode: an edge per distinct source and destination
+// pair, its width and opacity growing with the number of routes it stands
+// for, and a marker per distinct end.
+func (p *plotter) addFlows(routes []route) {
+	opts := p.opts
+
+	edges := flowEdges(routes, opts.precision)
+	if len(edges) == 0 {
+		return
+	}
+	maxWeight := edges[len(edges)-1].Weight
+
+	ends := map[s2.LatLng]bool{}
+	// an end shared by several edges is sized by the first row drawing it
+	addEnd := func(ll s2.LatLng, c color.RGBA, record []string) {
+		lat, lng := opts.precision.round(ll)
+		at := s2.LatLngFromDegrees(lat, lng)
+		if ends[at] {
+			return
+		}
+		ends[at] = true
+		m := sm.NewMarker(ll, c, opts.markerSize)
+		p.addMarker(m, record)
+		p.lyr.setID(m, fmt.Sprintf("flow.%d", len(ends)))
+	}
+
+	// -max-markers drops the lightest edges, not the heaviest
+	first := 0
+	for i := len(edges) - 1; i >= 0; i-- {
+		e := edges[i]
+		if markerCapReached(p.lyr, 2, e.Row, opts) {
+			first = i + 1
+			break
+		}
+		if opts.origin == nil {
+			addEnd(e.Src, opts.theme.Source, e.Record)
+		}
+		addEnd(e.Dst, opts.theme.Destination, e.Record)
+	}
+
+	for _, e := range edges[first:] {
+		width, c := flowStyle(e.Weight, maxWeight, opts.theme.Line, opts)
+		points := routePoints(e.Src, e.Dst, opts)
+		segments := [][]s2.LatLng{points}
+		if opts.wrap {
+			segments = splitPolyline(points)
+		}
+		for _, segment := range segments {
+			p.lyr.addPath(sm.NewPath(segment, c, width))
+		}
+		p.lyr.sources = append(p.lyr.sources, e.Src)
+	}
+
+	for _, rt := range routes {
+		p.diagnose(routeDiag(rt, nil))
+		dist := distanceMeters(rt.Src, rt.Dst, opts.distanceModel)
+		p.sum.Routes++
+		p.sum.TotalDistance += dist
+		if p.sum.Stats != nil {
+			p.sum.Stats.add(dist / 1000)
+		}
+	}
+
+	p.lyr.flowLegend = flowLegendSteps(maxWeight, opts)
+
+	fmt.Println(fmt.Sprintf("Flows: %d distinct edges from %d routes, max weight %d", len(edges), len(routes), maxWeight))
+	if first > 0 {
+		fmt.Println(fmt.Sprintf("Warning: %d lightest edges left out by -max-markers", first))
+	}
+}
+
+// flowLegendSteps picks the weights the width legend shows: 1, the middle
+// and the heaviest, without repeats.
+func flowLegendSteps(maxWeight int, opts options) []flowStep {
+	var steps []flowStep
+	for _, w := range []int{1, (maxWeight + 1) / 2, maxWeight} {
+		if len(steps) > 0 && steps[len(steps)-1].Weight >= w {
+			continue
+		}
+		width, c := flowStyle(w, maxWeight, opts.theme.Line, opts)
+		steps = append(steps, flowStep{Weight: w, Width: width, Color: c})
+	}
+
+	return steps
+}
+
+// drawFlowLegend overlays the flows mode width scale in the bottom-right
+// corner of img.
+func drawFlowLegend(img image.Image, steps []flowStep, stack cornerStack, t theme) image.Image {
+	dc := newOverlay(img)
+
+	const lineW = 30.0
+	labels := make([]string, len(steps))
+	labelW := 0.0
+	for i, s := range steps {
+		labels[i] = fmt.Sprintf("%d routes", s.Weight)
+		if s.Weight == 1 {
+			labels[i] = "1 route"
+		}
+		if w, _ := measureString(dc, labels[i]); w > labelW {
+			labelW = w
+		}
+	}
+
+	boxW := lineW + 4 + labelW + 2*legendPadding
+	boxH := float64(len(steps))*legendLineH + 2*legendPadding
+	imgW, imgH := logicalSize(dc)
+	x, y := stack.place(BottomRight, boxW, boxH, imgW, imgH)
+
+	dc.SetColor(t.Panel)
+	dc.DrawRectangle(x, y, boxW, boxH)
+	dc.Fill()
+
+	for i, s := range steps {
+		mid := y + legendPadding + float64(i)*legendLineH + legendLineH/2
+		dc.SetColor(s.Color)
+		dc.SetLineWidth(s.Width * pixelScale)
+		dc.DrawLine(x+legendPadding, mid, x+legendPadding+lineW, mid)
+		dc.Stroke()
+
+		dc.SetColor(t.Text)
+		dc.DrawStringAnchored(labels[i], x+legendPadding+lineW+4, mid, 0, 0.35)
+	}
+
+	return dc.Image()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestFlowsSizeCol(t *testing.T) {
+	at := func(lat, lng float64) s2.LatLng { return s2.LatLngFromDegrees(lat, lng) }
+
+	tests := []struct {
+		name    string
+		routes  []route
+		markers int
+	}{
+		{
+			name: "one edge",
+			routes: []route{
+				{Row: 1, Record: []string{"1"}, Src: at(1, 100), Dst: at(2, 101)},
+				{Row: 2, Record: []string{"2"}, Src: at(1, 100), Dst: at(2, 101)},
+			},
+			markers: 2,
+		},
+		{
+			name: "shared source",
+			routes: []route{
+				{Row: 1, Record: []string{"1"}, Src: at(1, 100), Dst: at(2, 101)},
+				{Row: 2, Record: []string{""}, Src: at(1, 100), Dst: at(3, 102)},
+			},
+			markers: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t)
+			opts.mode = "flows"
+			opts.sizeCol = 0
+
+			p := newPlotter(opts)
+			p.addFlows(tt.routes)
+			p.finish()
+
+			if len(p.lyr.markers) != tt.markers {
+				t.Fatalf("%d markers, want %d", len(p.lyr.markers), tt.markers)
+			}
+			for i, m := range p.lyr.markers {
+				if m.Size < opts.sizeMin || m.Size > opts.sizeMax {
+					t.Errorf("marker %d size %g outside -size-min and -size-max", i, m.Size)
+				}
+			}
+		})
+	}
+}
+
+func TestFlowStyle(t *testing.T) {
+	opts := testOptions(t)
+	red := color.RGBA{0xff, 0, 0, 0xff}
+
+	tests := []struct {
+		name              string
+		weight, maxWeight int
+		width             float64
+		over              color.RGBA // the line over white
+	}{
+		{name: "heaviest edge opaque", weight: 5, maxWeight: 5, width: 6, over: color.RGBA{0xff, 0, 0, 0xff}},
+		{name: "lightest edge faint but red", weight: 1, maxWeight: 5, width: 1, over: color.RGBA{0xff, 0xaf, 0xaf, 0xff}},
+		{name: "single weight opaque", weight: 1, maxWeight: 1, width: 6, over: color.RGBA{0xff, 0, 0, 0xff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, c := flowStyle(tt.weight, tt.maxWeight, red, opts)
+			if width != tt.width {
+				t.Errorf("width %g, want %g", width, tt.width)
+			}
+			if got := overWhite(c); got != tt.over {
+				t.Errorf("over white %v, want %v", got, tt.over)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// focusBounds returns the extent of the densest -heatmap-cell cells that
+// together hold at least pct percent of the points, so a few far-flung
+// points don't dictate the framing.
+func focusBounds(points []s2.LatLng, cellDeg, pct float64) s2.Rect {
+	r := s2.EmptyRect()
+	if len(points) == 0 {
+		return r
+	}
+
+	need := int(math.Ceil(float64(len(points)) * pct / 100))
+	covered := 0
+	for _, b := range binPoints(points, cellDeg) {
+		if covered >= need {
+			break
+		}
+		covered += b.Count
+
+		r = r.AddPoint(s2.LatLngFromDegrees(b.Lat-cellDeg/2, b.Lng-cellDeg/2))
+		r = r.AddPoint(s2.LatLngFromDegrees(b.Lat+cellDeg/2, b.Lng+cellDeg/2))
+	}
+
+	return r
+}
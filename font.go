@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fogleman/gg"
+)
+
+// FontSize is the size in points of text overlays drawn with -font or a
+// system font, about the height of gg's built-in 7x13 face, before
+// -scale.
+const FontSize = 12
+
+// SystemFonts are tried, in order, when -font is unset or fails to load.
+// They cover Cyrillic and Greek besides Latin, the built-in face only
+// ASCII; scripts like Devanagari need a -font such as Noto Sans
+// Devanagari, and gg draws them unshaped.
+var SystemFonts = []string{
+	"/usr/share/fonts/truetype/noto/NotoSans-Regular.ttf",
+	"/usr/share/fonts/noto/NotoSans-Regular.ttf",
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/TTF/DejaVuSans.ttf",
+	"/Library/Fonts/Arial Unicode.ttf",
+	"C:\\Windows\\Fonts\\arialuni.ttf",
+}
+
+// textFont is the TTF text overlays are drawn in; empty keeps gg's
+// built-in face.
+var textFont string
+
+// loadTextFont picks the font of the text overlays: path, or the first
+// system font that loads. A -font that can't be loaded is a warning, not
+// an error; the text is still drawn, only in the fallback.
+func loadTextFont(path string) {
+	if path != "" {
+		err := gg.NewContext(1, 1).LoadFontFace(path, FontSize)
+		if err == nil {
+			textFont = path
+			return
+		}
+		fmt.Println(fmt.Sprintf("Warning: -font %s not loaded (%v), falling back", path, err))
+	}
+
+	for _, f := range SystemFonts {
+		if err := gg.NewContext(1, 1).LoadFontFace(f, FontSize); err == nil {
+			textFont = f
+			return
+		}
+	}
+
+	if path != "" {
+		fmt.Println("Warning: no system font found, using the built-in face, which only covers Latin text")
+	}
+}
+
+// useTextFont sets dc to draw in the text overlay font.
+func useTextFont(dc *gg.Context) *gg.Context {
+	if textFont != "" {
+		// loaded once by loadTextFont already, so this can't fail
+		_ = dc.LoadFontFace(textFont, FontSize*pixelScale)
+	}
+
+	return dc
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	sm "github.com/flopp/go-staticmaps"
+)
+
+// mergeRepeats keeps one marker per location, grouping positions at the
+// shared -precision, and grows each survivor by the log of how many
+// markers it stands for. It returns the largest multiplicity and the
+// marker holding it.
+func mergeRepeats(lyr *layer, prec precision) (int, *sm.Marker) {
+	type key struct{ lat, lng float64 }
+
+	counts := map[key]int{}
+	var kept []*sm.Marker
+	for _, m := range lyr.markers {
+		lat, lng := prec.round(m.Position)
+		k := key{lat, lng}
+		if counts[k] == 0 {
+			kept = append(kept, m)
+		}
+		counts[k]++
+	}
+
+	// walk kept rather than the map so ties go to the first in the file
+	maxCount, maxMarker := 0, (*sm.Marker)(nil)
+	for _, m := range kept {
+		lat, lng := prec.round(m.Position)
+		n := counts[key{lat, lng}]
+		m.Size *= 1 + math.Log(float64(n))
+		if n > maxCount {
+			maxCount, maxMarker = n, m
+		}
+	}
+
+	lyr.markers = kept
+	return maxCount, maxMarker
+}
+
+// reportRepeats prints the largest -freq-size multiplicity.
+func reportRepeats(n int, m *sm.Marker) {
+	if m == nil {
+		return
+	}
+
+	fmt.Println(fmt.Sprintf("Max multiplicity: %d at %f,%f", n, m.Position.Lat.Degrees(), m.Position.Lng.Degrees()))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Geocoder when the query has no match.
+var ErrNotFound = errors.New("Location not found")
+
+// Geocoder resolves a free-form address or postal code to lat, lng.
+type Geocoder interface {
+	Geocode(query string) (float64, float64, error)
+}
+
+// NominatimURL is the public OpenStreetMap Nominatim search endpoint.
+const NominatimURL = "https://nominatim.openstreetmap.org/search"
+
+// nominatim is a Geocoder backed by a Nominatim server. Requests are spaced
+// at least interval apart, as the public instance allows one per second.
+type nominatim struct {
+	baseURL  string
+	client   *http.Client
+	interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newNominatim(baseURL string) *nominatim {
+	return &nominatim{
+		baseURL:  baseURL,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		interval: time.Second,
+	}
+}
+
+func (n *nominatim) Geocode(query string) (float64, float64, error) {
+	n.mu.Lock()
+	if wait := n.interval - time.Since(n.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	n.last = time.Now()
+	n.mu.Unlock()
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	params.Set("limit", "1")
+
+	req, err := http.NewRequest("GET", n.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("User-Agent", "courierInfo")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("geocoding %q: %s", query, resp.Status)
+	}
+
+	var places []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
+		return 0, 0, err
+	}
+	if len(places) == 0 {
+		return 0, 0, ErrNotFound
+	}
+
+	lat, err := strconv.ParseFloat(places[0].Lat, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lng, err := strconv.ParseFloat(places[0].Lon, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return lat, lng, nil
+}
+
+type geocodeResult struct {
+	lat, lng float64
+	err      error
+}
+
+// cachingGeocoder remembers results, including failures, so repeated
+// addresses are looked up only once.
+type cachingGeocoder struct {
+	next  Geocoder
+	cache map[string]geocodeResult
+}
+
+func newCachingGeocoder(next Geocoder) *cachingGeocoder {
+	return &cachingGeocoder{next: next, cache: map[string]geocodeResult{}}
+}
+
+func (c *cachingGeocoder) Geocode(query string) (float64, float64, error) {
+	key := strings.ToLower(strings.TrimSpace(query))
+	if r, ok := c.cache[key]; ok {
+		return r.lat, r.lng, r.err
+	}
+
+	lat, lng, err := c.next.Geocode(query)
+	c.cache[key] = geocodeResult{lat, lng, err}
+	return lat, lng, err
+}
+
+// isMissingCoordinate reports whether a coordinate cell holds no location.
+func isMissingCoordinate(cell string) bool {
+	if strings.Trim(cell, ", ") == "" {
+		return true
+	}
+
+	xy := strings.Split(cell, ",")
+	if len(xy) != 2 {
+		return false
+	}
+	x, errX := parseCoordinate(xy[0])
+	y, errY := parseCoordinate(xy[1])
+	return errX == nil && errY == nil && isSentinel(x, y)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeGeocoder resolves queries from a fixed map and counts lookups.
+type fakeGeocoder struct {
+	places map[string][2]float64
+	calls  int
+}
+
+func (f *fakeGeocoder) Geocode(query string) (float64, float64, error) {
+	f.calls++
+	p, ok := f.places[query]
+	if !ok {
+		return 0, 0, ErrNotFound
+	}
+
+	return p[0], p[1], nil
+}
+
+func TestLocateGeocodeFallback(t *testing.T) {
+	fake := &fakeGeocoder{places: map[string][2]float64{
+		"10110":  {-6.18, 106.83},
+		"London": {51.5, -0.12},
+	}}
+	opts := testOptions(t)
+	opts.geocoder = newCachingGeocoder(fake)
+
+	tests := []struct {
+		name    string
+		record  []string
+		lat     float64
+		wantErr error
+	}{
+		{name: "coordinates win", record: []string{"-6.2,106.8", "10110"}, lat: -6.2},
+		{name: "empty cell", record: []string{"", "10110"}, lat: -6.18},
+		{name: "comma only cell", record: []string{",", "10110"}, lat: -6.18},
+		{name: "sentinel cell", record: []string{"-999,-999", "10110"}, lat: -6.18},
+		{name: "empty query", record: []string{"", " "}, wantErr: ErrLatLong},
+		{name: "no match", record: []string{"", "nowhere"}, wantErr: ErrNotFound},
+		{name: "match out of range", record: []string{"", "London"}, wantErr: ErrLatLongOutOfRange},
+	}
+
+	for _, tt := range tests {
+		lat, _, err := locate(tt.record, 0, 1, opts)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: locate error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || lat != tt.lat {
+			t.Errorf("%s: locate = %v, %v, want %v", tt.name, lat, err, tt.lat)
+		}
+	}
+
+	// "10110" was looked up once and then served from the cache, the
+	// failures are cached too.
+	if fake.calls != 3 {
+		t.Errorf("geocoder called %d times, want 3", fake.calls)
+	}
+}
+
+func TestNominatim(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case "10110":
+			fmt.Fprint(w, `[{"lat":"-6.18","lon":"106.83"}]`)
+		case "broken":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer srv.Close()
+
+	n := newNominatim(srv.URL)
+	n.interval = 0
+
+	tests := []struct {
+		query    string
+		lat, lng float64
+		wantErr  bool
+	}{
+		{query: "10110", lat: -6.18, lng: 106.83},
+		{query: "nowhere", wantErr: true},
+		{query: "broken", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		lat, lng, err := n.Geocode(tt.query)
+		if (err != nil) != tt.wantErr || lat != tt.lat || lng != tt.lng {
+			t.Errorf("Geocode(%q) = %v, %v, %v", tt.query, lat, lng, err)
+		}
+	}
+}
+
+func TestIsMissingCoordinate(t *testing.T) {
+	tests := []struct {
+		cell string
+		want bool
+	}{
+		{"", true},
+		{" , ", true},
+		{"-999,-999", true},
+		{" -999.0 , -999 ", true},
+		{"-6.2,106.8", false},
+		{"0,0", false},
+		{"street 1, city, country", false},
+	}
+
+	for _, tt := range tests {
+		if got := isMissingCoordinate(tt.cell); got != tt.want {
+			t.Errorf("isMissingCoordinate(%q) = %v, want %v", tt.cell, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// GeodesicStepKm is the approximate spacing of the points interpolated
+// along a -geodesic route.
+const GeodesicStepKm = 50.0
+
+// greatCircle returns points along the great circle from a to b, both
+// included, spaced about GeodesicStepKm apart.
+func greatCircle(a, b s2.LatLng) []s2.LatLng {
+	lat1, lng1 := a.Lat.Radians(), a.Lng.Radians()
+	lat2, lng2 := b.Lat.Radians(), b.Lng.Radians()
+
+	// central angle via the haversine formula
+	d := 2 * math.Asin(math.Sqrt(math.Pow(math.Sin((lat2-lat1)/2), 2)+
+		math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin((lng2-lng1)/2), 2)))
+	if d == 0 {
+		return []s2.LatLng{a, b}
+	}
+
+	n := int(math.Ceil(d * EarthRadiusMeters / 1000 / GeodesicStepKm))
+	if n < 1 {
+		n = 1
+	}
+
+	points := make([]s2.LatLng, 0, n+1)
+	points = append(points, a)
+	for i := 1; i < n; i++ {
+		f := float64(i) / float64(n)
+		p := math.Sin((1-f)*d) / math.Sin(d)
+		q := math.Sin(f*d) / math.Sin(d)
+
+		x := p*math.Cos(lat1)*math.Cos(lng1) + q*math.Cos(lat2)*math.Cos(lng2)
+		y := p*math.Cos(lat1)*math.Sin(lng1) + q*math.Cos(lat2)*math.Sin(lng2)
+		z := p*math.Sin(lat1) + q*math.Sin(lat2)
+
+		lat := math.Atan2(z, math.Hypot(x, y))
+		lng := math.Atan2(y, x)
+		points = append(points, s2.LatLngFromDegrees(lat*180/math.Pi, lng*180/math.Pi))
+	}
+
+	return append(points, b)
+}
+
+// splitPolyline splits a polyline wherever a leg crosses the antimeridian,
+// like splitAntimeridian does for a single leg.
+func splitPolyline(points []s2.LatLng) [][]s2.LatLng {
+	if len(points) < 2 {
+		return [][]s2.LatLng{points}
+	}
+
+	var segments [][]s2.LatLng
+	current := []s2.LatLng{points[0]}
+	for i := 1; i < len(points); i++ {
+		legs := splitAntimeridian(points[i-1], points[i])
+		current = append(current, legs[0][1])
+		if len(legs) == 2 {
+			segments = append(segments, current)
+			current = legs[1]
+		}
+	}
+
+	return append(segments, current)
+}
+
+// widthForDistance maps meters linearly onto [minWidth, maxWidth] over
+// the -distance-min/-distance-max range, clamping outside it.
+func widthForDistance(meters, minKm, maxKm, minWidth, maxWidth float64) float64 {
+	t := 0.0
+	if maxKm > minKm {
+		t = (meters/1000 - minKm) / (maxKm - minKm)
+	}
+	t = math.Max(0, math.Min(1, t))
+
+	return minWidth + t*(maxWidth-minWidth)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+)
+
+// Coordinate types
+const (
+	CoordLatLng  = "latlng"
+	CoordGeohash = "geohash"
+)
+
+// Units of latlng cells
+const (
+	UnitDegrees = "degrees"
+	UnitRadians = "radians"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// decodeGeohash returns the center of the geohash cell as lat, lng.
+func decodeGeohash(hash string) (float64, float64, error) {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if hash == "" {
+		return 0, 0, ErrLatLong
+	}
+
+	latLo, latHi := -90.0, 90.0
+	lngLo, lngHi := -180.0, 180.0
+	even := true
+
+	for _, c := range hash {
+		idx := strings.IndexRune(geohashAlphabet, c)
+		if idx < 0 {
+			return 0, 0, ErrLatLong
+		}
+
+		for bit := 4; bit >= 0; bit-- {
+			set := idx&(1<<uint(bit)) != 0
+			if even {
+				mid := (lngLo + lngHi) / 2
+				if set {
+					lngLo = mid
+				} else {
+					lngHi = mid
+				}
+			} else {
+				mid := (latLo + latHi) / 2
+				if set {
+					latLo = mid
+				} else {
+					latHi = mid
+				}
+			}
+			even = !even
+		}
+	}
+
+	return (latLo + latHi) / 2, (lngLo + lngHi) / 2, nil
+}
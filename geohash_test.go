@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestDecodeGeohash(t *testing.T) {
+	tests := []struct {
+		hash     string
+		lat, lng float64
+		tol      float64
+		wantErr  bool
+	}{
+		{hash: "ezs42", lat: 42.605, lng: -5.603, tol: 0.03},
+		{hash: "u4pruydqqvj", lat: 57.64911, lng: 10.40744, tol: 1e-5},
+		{hash: " U4PRUYDQQVJ ", lat: 57.64911, lng: 10.40744, tol: 1e-5},
+		{hash: "s", lat: 22.5, lng: 22.5, tol: 0},
+		{hash: "", wantErr: true},
+		{hash: "u4pa", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		lat, lng, err := decodeGeohash(tt.hash)
+		if tt.wantErr {
+			if !errors.Is(err, ErrLatLong) {
+				t.Errorf("decodeGeohash(%q) error = %v, want ErrLatLong", tt.hash, err)
+			}
+			continue
+		}
+		if err != nil || math.Abs(lat-tt.lat) > tt.tol || math.Abs(lng-tt.lng) > tt.tol {
+			t.Errorf("decodeGeohash(%q) = %v, %v, %v, want %v, %v", tt.hash, lat, lng, err, tt.lat, tt.lng)
+		}
+	}
+}
+
+func TestParseLocationGeohash(t *testing.T) {
+	opts := testOptions(t)
+	opts.coordType = CoordGeohash
+
+	if _, _, err := parseLocation("ezs42", opts); !errors.Is(err, ErrLatLongOutOfRange) {
+		t.Errorf("parseLocation(ezs42) error = %v, want ErrLatLongOutOfRange", err)
+	}
+
+	lat, lng, err := parseLocation("qqguw", opts)
+	if err != nil || math.Abs(lat+6.2) > 0.05 || math.Abs(lng-106.8) > 0.05 {
+		t.Errorf("parseLocation(qqguw) = %v, %v, %v, want about -6.2, 106.8", lat, lng, err)
+	}
+}
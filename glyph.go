@@ -0,0 +1,91 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/fogleman/gg"
+)
+
+// glyphOf returns the first character of a -glyph-col value, or "" when
+// the value is empty.
+func glyphOf(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+
+	r, _ := utf8.DecodeRuneInString(value)
+	return string(r)
+}
+
+// drawGlyphs draws every glyph marker as its character centered on the
+// position over a disc of the marker color.
+func drawGlyphs(img image.Image, lyr *layer, vp Viewport) image.Image {
+	dc := useTextFont(gg.NewContextForImage(img))
+
+	for _, m := range lyr.markers {
+		glyph, ok := lyr.glyphs[m]
+		if !ok {
+			continue
+		}
+
+		x, y := vp.Project(m.Position)
+		w, h := dc.MeasureString(glyph)
+		r := math.Max(math.Max(w, h)/2+2, m.Size)
+
+		dc.DrawCircle(x, y, r)
+		dc.SetColor(m.Color)
+		dc.FillPreserve()
+		dc.SetRGB(0, 0, 0)
+		dc.SetLineWidth(1)
+		dc.Stroke()
+
+		dc.SetColor(contrastColor(m.Color))
+		dc.DrawStringAnchored(glyph, x, y, 0.5, 0.35)
+	}
+
+	return dc.Image()
+}
+
+// contrastColor returns black or white, whichever reads better on c.
+func contrastColor(c color.Color) color.Color {
+	r, g, b, _ := c.RGBA()
+	luma := 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+	if luma > 0.5*0xffff {
+		return color.Black
+	}
+
+	return color.White
+}
+
+// plainMarkers returns the markers drawn as regular pins, i.e. without a
+// glyph and not hollow.
+func (l *layer) plainMarkers() []*sm.Marker {
+	if len(l.glyphs) == 0 && len(l.hollow) == 0 {
+		return l.markers
+	}
+
+	markers := make([]*sm.Marker, 0, len(l.markers))
+	for _, m := range l.markers {
+		if _, ok := l.glyphs[m]; !ok && !l.hollow[m] {
+			markers = append(markers, m)
+		}
+	}
+
+	return markers
+}
+
+func (l *layer) setGlyph(m *sm.Marker, glyph string) {
+	if l.spilled(m) {
+		return
+	}
+	if l.glyphs == nil {
+		l.glyphs = map[*sm.Marker]string{}
+	}
+	l.glyphs[m] = glyph
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// desaturate returns img in grays of the same luminance, alpha kept.
+func desaturate(img image.Image) image.Image {
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	draw.Draw(out, b, img, b.Min, draw.Src)
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := out.RGBAAt(x, y)
+			// Rec. 601 luma, as color.GrayModel uses
+			l := uint8((19595*uint32(c.R) + 38470*uint32(c.G) + 7471*uint32(c.B) + 1<<15) >> 16)
+			out.SetRGBA(x, y, color.RGBA{l, l, l, c.A})
+		}
+	}
+
+	return out
+}
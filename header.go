@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// targetCells returns the cells of the coordinate columns a row is read
+// from, skipping columns the row doesn't have.
+func targetCells(record []string, opts options) []string {
+	cols := []int{opts.srcCol, opts.dstCol}
+	if opts.waypointsCol >= 0 {
+		cols = []int{opts.waypointsCol}
+	} else if opts.origin != nil {
+		cols = []int{opts.dstCol}
+	} else if opts.roleCol >= 0 {
+		cols = []int{opts.srcCol}
+	}
+
+	var cells []string
+	for _, col := range cols {
+		if col >= 0 && col < len(record) {
+			cells = append(cells, record[col])
+		}
+	}
+
+	return cells
+}
+
+// parsesAsCoordinates reports whether every coordinate cell of the row is
+// a valid location.
+func parsesAsCoordinates(record []string, opts options) bool {
+	cells := targetCells(record, opts)
+	if len(cells) == 0 {
+		return false
+	}
+
+	for _, cell := range cells {
+		if opts.waypointsCol >= 0 {
+			if stops, _ := parseWaypoints(cell, opts); len(stops) == 0 {
+				return false
+			}
+			continue
+		}
+
+		if _, _, err := parseLocation(cell, opts); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// looksLikeHeader reports whether the row's coordinate cells are text
+// without a single digit, as column names usually are.
+func looksLikeHeader(record []string, opts options) (string, bool) {
+	cells := targetCells(record, opts)
+	if len(cells) == 0 {
+		return "", false
+	}
+
+	for _, cell := range cells {
+		cell = strings.TrimSpace(cell)
+		if cell == "" || strings.IndexFunc(cell, unicode.IsDigit) >= 0 {
+			return "", false
+		}
+		if _, _, err := parseLocation(cell, opts); err == nil {
+			return "", false
+		}
+	}
+
+	return cells[0], true
+}
+
+// checkHeaderRow warns when a row taken as a header parses as data, a
+// sign -header-rows is too high.
+func checkHeaderRow(record []string, row int, opts options) {
+	if parsesAsCoordinates(record, opts) {
+		fmt.Println(fmt.Sprintf("Warning: header row %d parses as coordinates and may be data; check -header-rows (%d)", row, opts.headerRows))
+	}
+}
+
+// checkFirstDataRow warns when the first data row looks like column
+// names, a sign -header-rows is too low.
+func checkFirstDataRow(record []string, row int, opts options) {
+	if cell, ok := looksLikeHeader(record, opts); ok {
+		fmt.Println(fmt.Sprintf("Warning: first data row %d holds text like %q and may be a header; check -header-rows (%d)", row, cell, opts.headerRows))
+	}
+}
+
+// listColumns names every column by index for a failed precheck: the
+// header name, or the first data row's cell without a header, marked when
+// the first data row holds a location there. It shows which -src-col and
+// -dst-col were meant.
+func listColumns(header, record []string, opts options) string {
+	n := len(record)
+	if len(header) > n {
+		n = len(header)
+	}
+
+	var b strings.Builder
+	b.WriteString("Columns (index, name):")
+	for i := 0; i < n; i++ {
+		name := ""
+		if i < len(header) {
+			name = strings.TrimSpace(header[i])
+		} else if len(header) == 0 && i < len(record) {
+			name = fmt.Sprintf("%q", record[i])
+		}
+		if name == "" {
+			name = "(unnamed)"
+		}
+
+		fmt.Fprintf(&b, "\n  %d  %s", i, name)
+		if i < len(record) {
+			if _, _, err := parseLocation(record[i], opts); err == nil {
+				b.WriteString("  (holds a location in the first data row)")
+			}
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListColumns(t *testing.T) {
+	tests := []struct {
+		name           string
+		header, record []string
+		want           string
+	}{
+		{
+			name:   "header names",
+			header: []string{"id", "seller", " buyer "},
+			record: []string{"1", "-6.2,106.8", "x"},
+			want: "Columns (index, name):\n  0  id\n" +
+				"  1  seller  (holds a location in the first data row)\n" +
+				"  2  buyer",
+		},
+		{
+			name:   "no header",
+			record: []string{"1", "-6.2,106.8"},
+			want: "Columns (index, name):\n  0  \"1\"\n" +
+				"  1  \"-6.2,106.8\"  (holds a location in the first data row)",
+		},
+		{
+			name:   "row longer than header",
+			header: []string{"id", ""},
+			record: []string{"1", "2", "-6.2,106.8"},
+			want: "Columns (index, name):\n  0  id\n  1  (unnamed)\n" +
+				"  2  (unnamed)  (holds a location in the first data row)",
+		},
+		{
+			name:   "header longer than row",
+			header: []string{"id", "seller"},
+			record: []string{"1"},
+			want:   "Columns (index, name):\n  0  id\n  1  seller",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := listColumns(tt.header, tt.record, testOptions(t)); got != tt.want {
+			t.Errorf("%s: listColumns =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPrecheckListsColumns(t *testing.T) {
+	opts := testOptions(t)
+	opts.srcCol, opts.dstCol = 1, 2
+	opts.filename = filepath.Join(t.TempDir(), "in.csv")
+	if err := ioutil.WriteFile(opts.filename, []byte(sampleCSV), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := markLocations(opts)
+	if !errors.Is(err, ErrBadInput) {
+		t.Fatalf("error = %v, want ErrBadInput", err)
+	}
+	for _, line := range []string{
+		"\n  1  a\n",
+		"\n  9  seller_coordinates  (holds a location in the first data row)\n",
+		"\n  12  buyer_coordinates  (holds a location in the first data row)",
+	} {
+		if !strings.Contains(err.Error(), line) {
+			t.Errorf("error %q lacks %q", err, line)
+		}
+	}
+}
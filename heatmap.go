@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/csv"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s2"
+)
+
+// heatBin is one grid cell of the heatmap.
+type heatBin struct {
+	Lat, Lng float64 // cell center
+	Count    int
+}
+
+type binKey struct{ i, j int64 }
+
+// binPoints counts points per square grid cell of cellDeg degrees. Bins are
+// returned densest first, ties ordered by position so output is stable.
+func binPoints(points []s2.LatLng, cellDeg float64) []heatBin {
+	counts := map[binKey]int{}
+	for _, p := range points {
+		k := binKey{
+			int64(math.Floor(p.Lat.Degrees() / cellDeg)),
+			int64(math.Floor(p.Lng.Degrees() / cellDeg)),
+		}
+		counts[k]++
+	}
+
+	bins := make([]heatBin, 0, len(counts))
+	for k, n := range counts {
+		bins = append(bins, heatBin{
+			Lat:   (float64(k.i) + 0.5) * cellDeg,
+			Lng:   (float64(k.j) + 0.5) * cellDeg,
+			Count: n,
+		})
+	}
+
+	sort.Slice(bins, func(a, b int) bool {
+		if bins[a].Count != bins[b].Count {
+			return bins[a].Count > bins[b].Count
+		}
+		if bins[a].Lat != bins[b].Lat {
+			return bins[a].Lat < bins[b].Lat
+		}
+		return bins[a].Lng < bins[b].Lng
+	})
+
+	return bins
+}
+
+// markerPositions returns the position of every marker in the layer.
+func markerPositions(lyr *layer) []s2.LatLng {
+	points := make([]s2.LatLng, 0, len(lyr.markers))
+	for _, m := range lyr.markers {
+		points = append(points, m.Position)
+	}
+
+	return points
+}
+
+// heatmapLayer draws each bin as a filled cell shaded from green (sparse)
+// to red (densest).
+func heatmapLayer(bins []heatBin, cellDeg float64) *layer {
+	lyr := &layer{}
+	if len(bins) == 0 {
+		return lyr
+	}
+
+	max := float64(bins[0].Count)
+	half := cellDeg / 2
+	for _, b := range bins {
+		c := gradientColor(float64(b.Count) / max)
+		fill := color.NRGBA{c.R, c.G, c.B, 0xb0}
+
+		lyr.addArea(sm.NewArea([]s2.LatLng{
+			s2.LatLngFromDegrees(b.Lat-half, b.Lng-half),
+			s2.LatLngFromDegrees(b.Lat-half, b.Lng+half),
+			s2.LatLngFromDegrees(b.Lat+half, b.Lng+half),
+			s2.LatLngFromDegrees(b.Lat+half, b.Lng-half),
+		}, color.RGBA{0, 0, 0, 0}, fill, 0))
+	}
+
+	return lyr
+}
+
+// writeHeatmapCSV writes the bins as center lat, lng and count rows.
+func writeHeatmapCSV(w io.Writer, bins []heatBin) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"lat", "lng", "count"})
+	for _, b := range bins {
+		cw.Write([]string{
+			strconv.FormatFloat(b.Lat, 'f', -1, 64),
+			strconv.FormatFloat(b.Lng, 'f', -1, 64),
+			strconv.Itoa(b.Count),
+		})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// overWhite is what c looks like drawn over a white pixel.
+func overWhite(c color.Color) color.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Over)
+	return img.RGBAAt(0, 0)
+}
+
+func TestHeatmapLayerFill(t *testing.T) {
+	tests := []struct {
+		name string
+		bins []heatBin
+		want []color.RGBA // each cell over white
+	}{
+		{
+			name: "densest cell is red",
+			bins: []heatBin{{Lat: 1, Lng: 1, Count: 4}},
+			want: []color.RGBA{{0xff, 0x4f, 0x4f, 0xff}},
+		},
+		{
+			name: "sparsest cell is green",
+			bins: []heatBin{{Lat: 1, Lng: 1, Count: 4}, {Lat: 2, Lng: 2, Count: 0}},
+			want: []color.RGBA{{0xff, 0x4f, 0x4f, 0xff}, {0x4f, 0xff, 0x4f, 0xff}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lyr := heatmapLayer(tt.bins, 0.1)
+			if len(lyr.areas) != len(tt.want) {
+				t.Fatalf("%d areas, want %d", len(lyr.areas), len(tt.want))
+			}
+			for i, a := range lyr.areas {
+				if got := overWhite(a.Fill); got != tt.want[i] {
+					t.Errorf("cell %d over white is %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
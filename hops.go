@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/fogleman/gg"
+	"github.com/golang/geo/s2"
+)
+
+// hopLabel is the -hop-labels count of a waypoints route, drawn at its
+// first stop.
+type hopLabel struct {
+	At   s2.LatLng
+	Hops int
+}
+
+// noteHops records the hop label of a route through stops; single-hop
+// routes get none.
+func (l *layer) noteHops(stops []s2.LatLng) {
+	if len(stops) < 3 {
+		return
+	}
+
+	l.hops = append(l.hops, hopLabel{At: stops[0], Hops: len(stops) - 1})
+}
+
+// drawHopLabels writes each route's hop count just below its first stop,
+// clear of the pin above it and of -draw-ids to its upper right.
+func drawHopLabels(img image.Image, labels []hopLabel, vp Viewport, t theme) image.Image {
+	dc := useTextFont(gg.NewContextForImage(img))
+	dc.SetColor(t.Text)
+
+	for _, h := range labels {
+		x, y := vp.Project(h.At)
+		dc.DrawStringAnchored(fmt.Sprintf("%d hops", h.Hops), x, y+3*pixelScale, 0.5, 1)
+	}
+
+	return dc.Image()
+}
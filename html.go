@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"image/color"
+	"io"
+)
+
+// geoJSON types, just enough for the embedded feature collection
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+func hexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+// layerGeoJSON converts the layer to GeoJSON; marker properties become the
+// feature properties alongside a color.
+func layerGeoJSON(lyr *layer) geoJSONCollection {
+	fc := geoJSONCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+
+	for _, p := range lyr.paths {
+		var coords [][]float64
+		for _, pos := range p.Positions {
+			coords = append(coords, []float64{pos.Lng.Degrees(), pos.Lat.Degrees()})
+		}
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "LineString", Coordinates: coords},
+			Properties: map[string]interface{}{"color": hexColor(p.Color)},
+		})
+	}
+
+	for _, m := range lyr.markers {
+		props := map[string]interface{}{"color": hexColor(m.Color)}
+		for k, v := range lyr.props[m] {
+			props[k] = v
+		}
+		id := lyr.ids[m]
+		if id != "" {
+			props["id"] = id
+		}
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type:       "Feature",
+			ID:         id,
+			Geometry:   geoJSONGeometry{Type: "Point", Coordinates: []float64{m.Position.Lng.Degrees(), m.Position.Lat.Degrees()}},
+			Properties: props,
+		})
+	}
+
+	return fc
+}
+
+// Popup content is built with textContent so cell values can never inject
+// markup; the GeoJSON itself is escaped by encoding/json.
+var htmlTemplate = template.Must(template.New("map").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="https://unpkg.com/leaflet@1.9.4/dist/leaflet.css">
+<script src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"></script>
+<style>html, body, #map { height: 100%; margin: 0; }</style>
+</head>
+<body>
+<div id="map"></div>
+<script>
+var data = {{.Data}};
+var map = L.map("map");
+L.tileLayer("https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png", {
+	attribution: "&copy; OpenStreetMap contributors"
+}).addTo(map);
+
+function popup(props) {
+	var div = document.createElement("div");
+	Object.keys(props).forEach(function (key) {
+		if (key === "color") {
+			return;
+		}
+		var line = document.createElement("div");
+		var name = document.createElement("b");
+		name.textContent = key + ": ";
+		line.appendChild(name);
+		line.appendChild(document.createTextNode(String(props[key])));
+		div.appendChild(line);
+	});
+	return div;
+}
+
+var features = L.geoJSON(data, {
+	style: function (f) { return { color: f.properties.color, weight: 1 }; },
+	pointToLayer: function (f, latlng) {
+{{- if .Pins}}
+		// a 16x24 pin anchored at its tip; the color is checked since a
+		// "color" column in the data would replace the marker's
+		var c = /^#[0-9a-f]{6}$/.test(f.properties.color) ? f.properties.color : "#3388ff";
+		return L.marker(latlng, { icon: L.divIcon({
+			className: "",
+			html: '<svg width="16" height="24" viewBox="0 0 16 24"><path d="M8 24 L1.2 12 A7.8 7.8 0 1 1 14.8 12 Z" fill="' + c + '" stroke="#000"/></svg>',
+			iconSize: [16, 24],
+			iconAnchor: [8, 24],
+			popupAnchor: [0, -20]
+		}) });
+{{- else}}
+		return L.circleMarker(latlng, { radius: 4, color: f.properties.color, fillOpacity: 0.9 });
+{{- end}}
+	},
+	onEachFeature: function (f, l) {
+		if (f.geometry.type === "Point") {
+			l.bindPopup(popup(f.properties));
+		}
+	}
+}).addTo(map);
+
+if (data.features.length > 0) {
+	map.fitBounds(features.getBounds());
+} else {
+	map.setView([0, 0], 2);
+}
+</script>
+</body>
+</html>
+`))
+
+// writeHTML writes an interactive Leaflet page showing the layer, with
+// point markers as circles or as pins.
+func writeHTML(w io.Writer, lyr *layer, title string, pins bool) error {
+	data, err := json.Marshal(layerGeoJSON(lyr))
+	if err != nil {
+		return err
+	}
+
+	return htmlTemplate.Execute(w, struct {
+		Title string
+		Data  template.JS
+		Pins  bool
+	}{title, template.JS(data), pins})
+}
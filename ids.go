@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/fogleman/gg"
+)
+
+// markerIDBase is the part of a marker ID shared by a row's markers: the
+// first non-empty -id-col or -label-col value, otherwise the row number.
+func markerIDBase(record []string, row int, opts options) string {
+	for _, col := range []int{opts.idCol, opts.labelCol} {
+		if col >= 0 && col < len(record) {
+			if id := strings.TrimSpace(record[col]); id != "" {
+				return id
+			}
+		}
+	}
+
+	return strconv.Itoa(row)
+}
+
+func (l *layer) setID(m *sm.Marker, id string) {
+	if l.spilled(m) {
+		return
+	}
+	if l.ids == nil {
+		l.ids = map[*sm.Marker]string{}
+	}
+	l.ids[m] = id
+}
+
+// vectorPath returns the GeoJSON path written next to an image.
+func vectorPath(imagePath string) string {
+	return strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".geojson"
+}
+
+// writeVector writes the layer as GeoJSON next to the image, so its
+// feature IDs can be matched against the picture.
+func writeVector(imagePath string, lyr *layer) error {
+	data, err := json.MarshalIndent(layerGeoJSON(lyr), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(vectorPath(imagePath), data, 0644)
+}
+
+// drawMarkerIDs writes each marker's ID in small text to its upper right.
+func drawMarkerIDs(img image.Image, lyr *layer, vp Viewport, t theme) image.Image {
+	dc := useTextFont(gg.NewContextForImage(img))
+	dc.SetColor(t.Text)
+
+	for _, m := range lyr.markers {
+		id, ok := lyr.ids[m]
+		if !ok {
+			continue
+		}
+
+		x, y := vp.Project(m.Position)
+		dc.DrawStringAnchored(id, x+m.Size/2+2, y-m.Size/2-2, 0, 0)
+	}
+
+	return dc.Image()
+}
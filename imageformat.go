@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+
+	"github.com/fogleman/gg"
+)
+
+// Image formats for -format in the rendering modes
+const (
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+// ErrNoWebP is returned for -format webp by builds without the encoder.
+var ErrNoWebP = fmt.Errorf("%w: -format webp needs a build with -tags webp", ErrBadInput)
+
+// imageFormat returns the format renders are written in; an empty -format
+// means PNG.
+func imageFormat(format string) (string, error) {
+	switch format {
+	case "", FormatPNG:
+		return FormatPNG, nil
+	case FormatWebP:
+		return FormatWebP, nil
+	}
+
+	return "", fmt.Errorf("%w: image format %q, expected %s or %s", ErrBadInput, format, FormatPNG, FormatWebP)
+}
+
+// saveImage writes img to filePath in -format.
+func saveImage(filePath string, img image.Image, opts options) error {
+	if opts.imageFormat != FormatWebP {
+		return gg.SavePNG(filePath, img)
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := encodeWebP(file, img, opts.quality); err != nil {
+		file.Close()
+		os.Remove(filePath)
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MergedSource is the input name of a -merge run, used in output names.
+const MergedSource = "merged"
+
+// expandInputs returns the -file value followed by the other arguments,
+// with glob patterns such as "data/*.csv" replaced by their matches in
+// lexical order. Arguments the shell already expanded pass through.
+func expandInputs(file string, args []string) ([]string, error) {
+	if file != "" {
+		args = append([]string{file}, args...)
+	}
+
+	var files []string
+	for _, arg := range args {
+		if arg == StdinSource || isURL(arg) || !strings.ContainsAny(arg, "*?[") {
+			files = append(files, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pattern %q: %v", ErrBadInput, arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%w: no files match %q", ErrBadInput, arg)
+		}
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
+
+// mergeOpener serves every input as one stream for -merge: the first
+// input whole, then the others without their header rows. Header rows are
+// read as CSV records, as a single input's are, so comment and directive
+// lines and quoted newlines don't throw the count off.
+type mergeOpener struct {
+	files      []string
+	headerRows int
+	comment    rune
+	delimiter  rune
+	lazyQuotes bool
+}
+
+func (o mergeOpener) Open(string) (io.ReadCloser, error) {
+	return &mergedReader{opener: o}, nil
+}
+
+// mergedReader opens the inputs one after another as they are read.
+type mergedReader struct {
+	opener mergeOpener
+	next   int
+	cur    io.ReadCloser
+	br     *bufio.Reader
+
+	// last byte read from the current input, to end it with a newline
+	last byte
+
+	// offset in the merged stream where each input's rows start
+	pos    int64
+	starts []int64
+}
+
+func (r *mergedReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if r.next == len(r.opener.files) {
+				return 0, io.EOF
+			}
+
+			src := r.opener.files[r.next]
+			rc, ok := takeOpened(src)
+			if !ok {
+				var err error
+				if rc, err = defaultOpener(src).Open(src); err != nil {
+					return 0, err
+				}
+			}
+			r.cur, r.br, r.last = rc, bufio.NewReader(rc), '\n'
+			if r.next > 0 {
+				r.br = bufio.NewReader(r.opener.skipHeader(rc))
+			}
+			r.starts = append(r.starts, r.pos)
+			r.next++
+		}
+
+		n, err := r.br.Read(p)
+		if n > 0 {
+			r.last = p[n-1]
+			r.pos += int64(n)
+			return n, nil
+		}
+		if err == io.EOF {
+			r.cur.Close()
+			r.cur = nil
+			if r.last != '\n' && len(p) > 0 {
+				r.last = '\n'
+				p[0] = '\n'
+				r.pos++
+				return 1, nil
+			}
+			continue
+		}
+		return 0, err
+	}
+}
+
+// skipHeader returns rc past its header rows. The csv.Reader buffers ahead,
+// so what it read beyond the header is served again before the rest of rc.
+func (o mergeOpener) skipHeader(rc io.Reader) io.Reader {
+	var buf bytes.Buffer
+	header := csv.NewReader(io.TeeReader(rc, &buf))
+	header.Comment = o.comment
+	header.Comma = o.delimiter
+	header.LazyQuotes = o.lazyQuotes
+	header.FieldsPerRecord = -1
+
+	for i := 0; i < o.headerRows; i++ {
+		if _, err := header.Read(); err == io.EOF {
+			break
+		}
+	}
+
+	return io.MultiReader(bytes.NewReader(buf.Bytes()[header.InputOffset():]), rc)
+}
+
+// fileAt returns the input holding the record that ends at offset in the
+// merged stream, as given by csv.Reader.InputOffset.
+func (r *mergedReader) fileAt(offset int64) string {
+	i := sort.Search(len(r.starts), func(i int) bool { return r.starts[i] >= offset }) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return r.opener.files[i]
+}
+
+func (r *mergedReader) Close() error {
+	if r.cur != nil {
+		return r.cur.Close()
+	}
+
+	return nil
+}
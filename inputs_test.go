@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergedReaderSkipsHeaders(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	first := write("a.csv", "id,note\n1,a\n")
+	tests := []struct {
+		name    string
+		content string
+		rest    string // after the first input
+	}{
+		{name: "plain", content: "id,note\n2,b\n", rest: "2,b\n"},
+		{name: "directive", content: "#courierinfo: theme=dark\nid,note\n2,b\n", rest: "2,b\n"},
+		{name: "comment after header", content: "id,note\n# exported nightly\n2,b\n", rest: "# exported nightly\n2,b\n"},
+		{name: "quoted newline", content: "id,\"note\nmore\"\n2,b\n", rest: "2,b\n"},
+		{name: "no newline", content: "id,note\n2,b", rest: "2,b\n"},
+	}
+
+	for _, tt := range tests {
+		second := write(tt.name+".csv", tt.content)
+		rc, err := mergeOpener{files: []string{first, second}, headerRows: 1, comment: '#', delimiter: ','}.Open(MergedSource)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if want := "id,note\n1,a\n" + tt.rest; string(got) != want {
+			t.Errorf("%s: merged = %q, want %q", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/bmishra/courierInfo/mapview"
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/fogleman/gg"
+	"github.com/golang/geo/s2"
+)
+
+// insetFrame marks the main map's extent on the inset
+var insetFrame = color.RGBA{0xe4, 0x1a, 0x1c, 0xff}
+
+// countryRect is the accepted coordinate range as a rect, the extent the
+// inset shows.
+func countryRect() s2.Rect {
+	b := activeBounds
+	r := s2.RectFromLatLng(s2.LatLngFromDegrees(b.MinLat, b.MinLng))
+	return r.AddPoint(s2.LatLngFromDegrees(b.MaxLat, b.MaxLng))
+}
+
+// renderInset renders the country extent at width pixels wide, in the main
+// map's aspect ratio, with a rectangle around the area vp shows.
+func renderInset(vp Viewport, width int, opts options) (image.Image, error) {
+	height := width * vp.Height / vp.Width
+	ivp := mapview.FitViewport(countryRect(), width, height, 2)
+
+	ctx := sm.NewContext()
+	ctx.SetSize(ivp.Width, ivp.Height)
+	ctx.SetCenter(ivp.Center)
+	ctx.SetZoom(ivp.Zoom)
+	if tp, _ := lookupTileProvider(opts.tiles); tp != nil {
+		ctx.SetTileProvider(tp)
+	}
+
+	tiles, err := renderBefore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.grayscale {
+		tiles = desaturate(tiles)
+	}
+	dc := gg.NewContextForImage(tiles)
+
+	x0, y0 := ivp.Project(vp.Unproject(0, 0))
+	x1, y1 := ivp.Project(vp.Unproject(float64(vp.Width), float64(vp.Height)))
+
+	// a frame too small to see is drawn as a box around its center
+	minFrame := 6 * pixelScale
+	if x1-x0 < minFrame {
+		cx := (x0 + x1) / 2
+		x0, x1 = cx-minFrame/2, cx+minFrame/2
+	}
+	if y1-y0 < minFrame {
+		cy := (y0 + y1) / 2
+		y0, y1 = cy-minFrame/2, cy+minFrame/2
+	}
+
+	dc.SetColor(insetFrame)
+	dc.SetLineWidth(2 * pixelScale)
+	dc.DrawRectangle(x0, y0, x1-x0, y1-y0)
+	dc.Stroke()
+
+	return dc.Image(), nil
+}
+
+// drawInset composites the inset into corner of img with a border in the
+// theme's text color.
+func drawInset(img, inset image.Image, corner string, stack cornerStack, t theme) image.Image {
+	dc := newOverlay(img)
+
+	// the inset is rendered at pixelScale already
+	b := inset.Bounds()
+	w, h := float64(b.Dx())/pixelScale, float64(b.Dy())/pixelScale
+	imgW, imgH := logicalSize(dc)
+	x, y := stack.place(corner, w, h, imgW, imgH)
+
+	dc.Push()
+	dc.Identity()
+	dc.DrawImage(inset, int(x*pixelScale), int(y*pixelScale))
+	dc.Pop()
+
+	dc.SetColor(t.Text)
+	dc.SetLineWidth(pixelScale)
+	dc.DrawRectangle(x, y, w, h)
+	dc.Stroke()
+
+	return dc.Image()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	sm "github.com/flopp/go-staticmaps"
+)
+
+// jitterMarkers spreads markers sharing an exact position: the first stays
+// put and each further one moves up to radius pixels in a random direction.
+// Offsets come from seed and the marker order, so the same input and seed
+// always produce the same picture.
+func jitterMarkers(markers []*sm.Marker, vp Viewport, radius float64, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	seen := map[[2]float64]bool{}
+
+	for _, m := range markers {
+		key := [2]float64{m.Position.Lat.Degrees(), m.Position.Lng.Degrees()}
+		if !seen[key] {
+			seen[key] = true
+			continue
+		}
+
+		angle := rng.Float64() * 2 * math.Pi
+		r := radius * math.Sqrt(rng.Float64())
+		x, y := vp.Project(m.Position)
+		m.Position = vp.Unproject(x+r*math.Cos(angle), y+r*math.Sin(angle))
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/bmishra/courierInfo/mapview"
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/fogleman/gg"
+	"github.com/golang/geo/s2"
+)
+
+// layer holds everything a run draws, independent of how it is rendered.
+type layer struct {
+	markers []*sm.Marker
+	paths   []*sm.Path
+	areas   []*sm.Area
+
+	// props holds per-marker attributes for the interactive and vector
+	// outputs, e.g. the row number and popup columns.
+	props map[*sm.Marker]map[string]string
+
+	// glyphs holds the character drawn instead of the pin for -glyph-col
+	// markers.
+	glyphs map[*sm.Marker]string
+
+	// ids holds the stable marker IDs shared by the image and the GeoJSON
+	// export.
+	ids map[*sm.Marker]string
+
+	// dash is the -dash pattern of the paths; empty draws them solid.
+	// pathDash overrides it per path, e.g. by -transport-styles.
+	dash     []float64
+	pathDash map[*sm.Path][]float64
+	style    elementStyle
+
+	// legend holds a swatch per -group-col group, -transport-col mode and
+	// -file-colors input, in first seen order; lineLegend, drawn on the
+	// map, just the modes and inputs.
+	legend     []legendEntry
+	lineLegend []legendEntry
+
+	// flowLegend holds the width scale of flows mode.
+	flowLegend []flowStep
+
+	// header and routes hold the plotted rows, for the midpoints and
+	// parquet outputs; routes stays empty in the other modes.
+	header []string
+	routes []route
+
+	// sources holds the start of every plotted route, for topsources mode.
+	sources []s2.LatLng
+
+	// hops holds the -hop-labels of the waypoints routes.
+	hops []hopLabel
+
+	// basemapOnly renders just the tiles of the view, for -grayscale.
+	basemapOnly bool
+
+	// spill holds the markers beyond the -spill threshold.
+	spill *spillFile
+
+	// scaled is set once scaleLayer has applied -scale.
+	scaled bool
+
+	// hollow marks the markers -role-style draws as rings.
+	hollow map[*sm.Marker]bool
+}
+
+func (l *layer) addMarker(m *sm.Marker) {
+	if l.spill != nil && len(l.markers) >= l.spill.after {
+		l.spill.add(m)
+		return
+	}
+	l.markers = append(l.markers, m)
+}
+
+func (l *layer) setProps(m *sm.Marker, props map[string]string) {
+	if l.spilled(m) {
+		return
+	}
+	if l.props == nil {
+		l.props = map[*sm.Marker]map[string]string{}
+	}
+	l.props[m] = props
+}
+
+func (l *layer) addPath(p *sm.Path) {
+	l.paths = append(l.paths, p)
+}
+
+func (l *layer) addArea(a *sm.Area) {
+	l.areas = append(l.areas, a)
+}
+
+func (l *layer) empty() bool {
+	return l.markerCount() == 0 && len(l.paths) == 0 && len(l.areas) == 0
+}
+
+// bounds returns the rectangle covering all markers and path positions.
+func (l *layer) bounds() s2.Rect {
+	r := s2.EmptyRect()
+	for _, m := range l.markers {
+		r = r.AddPoint(m.Position)
+	}
+	for _, p := range l.paths {
+		for _, pos := range p.Positions {
+			r = r.AddPoint(pos)
+		}
+	}
+	for _, a := range l.areas {
+		for _, pos := range a.Positions {
+			r = r.AddPoint(pos)
+		}
+	}
+	if l.spill != nil {
+		r = r.Union(l.spill.bounds)
+	}
+
+	return r
+}
+
+// margin is the pixel margin needed so the largest marker is not clipped.
+func (l *layer) margin() float64 {
+	margin := 4.0
+	for _, m := range l.markers {
+		margin = math.Max(margin, 4.0+1.5*m.Size)
+	}
+	if l.spill != nil && l.spill.count > 0 {
+		margin = math.Max(margin, 4.0+1.5*l.spill.maxSize)
+	}
+
+	return margin
+}
+
+// newMapContext builds a go-staticmaps context showing the layer at vp.
+func newMapContext(l *layer, vp Viewport) *sm.Context {
+	ctx := sm.NewContext()
+	ctx.SetSize(vp.Width, vp.Height)
+	if !l.empty() || l.basemapOnly {
+		ctx.SetCenter(vp.Center)
+		ctx.SetZoom(vp.Zoom)
+	}
+	if l.basemapOnly {
+		return ctx
+	}
+
+	for _, a := range l.areas {
+		ctx.AddArea(a)
+	}
+	// go-staticmaps can't dash paths or change their caps and joins, so
+	// such paths and the markers that go over them are left to
+	// drawLayer(img, l.overlay(), vp)
+	if l.drawnByGG() {
+		return ctx
+	}
+
+	for _, p := range l.paths {
+		ctx.AddPath(p)
+	}
+	for _, m := range l.plainMarkers() {
+		ctx.AddMarker(m)
+	}
+
+	return ctx
+}
+
+// dashed reports whether any path is drawn dashed.
+func (l *layer) dashed() bool {
+	if len(l.dash) > 0 {
+		return true
+	}
+	for _, d := range l.pathDash {
+		if len(d) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// drawnByGG reports whether the paths and markers are drawn by drawLayer
+// rather than go-staticmaps.
+func (l *layer) drawnByGG() bool {
+	return l.dashed() || l.style != defaultStyle || (l.spill != nil && l.spill.count > 0)
+}
+
+// dashOf returns the dash pattern of a path.
+func (l *layer) dashOf(p *sm.Path) []float64 {
+	if d, ok := l.pathDash[p]; ok {
+		return d
+	}
+
+	return l.dash
+}
+
+func (l *layer) setDash(p *sm.Path, dash []float64) {
+	if l.pathDash == nil {
+		l.pathDash = map[*sm.Path][]float64{}
+	}
+	l.pathDash[p] = dash
+}
+
+// overlay returns the part of a drawnByGG layer newMapContext leaves out.
+func (l *layer) overlay() *layer {
+	return &layer{markers: l.markers, paths: l.paths, glyphs: l.glyphs, dash: l.dash, pathDash: l.pathDash, style: l.style, spill: l.spill, hollow: l.hollow}
+}
+
+// drawLayer draws the layer onto img at vp using gg, matching the look of
+// go-staticmaps markers and paths.
+func drawLayer(img image.Image, l *layer, vp Viewport) image.Image {
+	dc := gg.NewContextForImage(img)
+	drawElements(dc, vp, l.areas, l.paths, l.plainMarkers(), l.dashOf, l.style)
+	for _, m := range l.hollowMarkers() {
+		drawHollowMarker(dc, m, vp)
+	}
+	if l.spill != nil && l.spill.count > 0 {
+		if err := l.spill.draw(dc, vp, l.style.Circles); err != nil {
+			fmt.Println(fmt.Sprintf("Warning: %v, the spilled markers are missing", err))
+		}
+	}
+	return dc.Image()
+}
+
+// drawElements draws like mapview.DrawOnto, with each path dashed by dashOf
+// when it is set, in style.
+func drawElements(dc *gg.Context, vp Viewport, areas []*sm.Area, paths []*sm.Path, markers []*sm.Marker, dashOf func(*sm.Path) []float64, style elementStyle) {
+	for _, a := range areas {
+		mapview.DrawArea(dc, a, vp)
+	}
+	style.Stroke.apply(dc)
+	for _, p := range paths {
+		if dashOf != nil {
+			dc.SetDash(dashOf(p)...)
+		}
+		mapview.DrawPath(dc, p, vp)
+	}
+	dc.SetDash()
+	for _, m := range markers {
+		mapview.DrawMarker(dc, m, vp, style.Circles)
+	}
+}
+
+// outlineMarkers strokes a contrasting outline around every marker of the
+// layer, already rendered onto img at vp.
+func outlineMarkers(img image.Image, l *layer, vp Viewport, c color.Color, width float64) image.Image {
+	dc := gg.NewContextForImage(img)
+
+	for _, m := range l.markers {
+		mapview.MarkerPath(dc, m, vp, l.style.Circles)
+		dc.SetColor(c)
+		dc.SetLineWidth(width)
+		dc.Stroke()
+	}
+
+	return dc.Image()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/fogleman/gg"
+)
+
+// Legend layout, in pixels
+const (
+	legendMargin  = 10.0
+	legendPadding = 6.0
+	legendBarW    = 120.0
+	legendBarH    = 10.0
+	legendSwatch  = 10.0
+	legendLineH   = 16.0
+)
+
+// legendEntry is one swatch of the group legend.
+type legendEntry struct {
+	Label string
+	Color color.RGBA
+}
+
+// distanceLegendSize is the size of the distance gradient box.
+func distanceLegendSize() (float64, float64) {
+	return legendBarW + 2*legendPadding, legendBarH + 2*legendPadding + 14
+}
+
+// groupLegendSize is the size of the group swatch box.
+func groupLegendSize(dc *gg.Context, entries []legendEntry) (float64, float64) {
+	w := 0.0
+	for _, e := range entries {
+		if lw, _ := measureString(dc, e.Label); lw > w {
+			w = lw
+		}
+	}
+
+	return legendSwatch + 4 + w + 2*legendPadding, float64(len(entries))*legendLineH + 2*legendPadding
+}
+
+// drawDistanceLegendAt draws the distance gradient scale with its top-left
+// corner at x, y.
+func drawDistanceLegendAt(dc *gg.Context, x, y, minKm, maxKm float64, t theme) {
+	boxW, boxH := distanceLegendSize()
+
+	dc.SetColor(t.Panel)
+	dc.DrawRectangle(x, y, boxW, boxH)
+	dc.Fill()
+
+	for i := 0; i < int(legendBarW); i++ {
+		dc.SetColor(gradientColor(float64(i) / (legendBarW - 1)))
+		dc.DrawRectangle(x+legendPadding+float64(i), y+legendPadding, 1, legendBarH)
+		dc.Fill()
+	}
+
+	dc.SetColor(t.Text)
+	labelY := y + legendPadding + legendBarH + 12
+	dc.DrawStringAnchored(fmt.Sprintf("%g km", minKm), x+legendPadding, labelY, 0, 0)
+	dc.DrawStringAnchored(fmt.Sprintf("%g km", maxKm), x+legendPadding+legendBarW, labelY, 1, 0)
+}
+
+// drawGroupLegendAt draws a swatch and label per group with its top-left
+// corner at x, y.
+func drawGroupLegendAt(dc *gg.Context, x, y float64, entries []legendEntry, t theme) {
+	boxW, boxH := groupLegendSize(dc, entries)
+
+	dc.SetColor(t.Panel)
+	dc.DrawRectangle(x, y, boxW, boxH)
+	dc.Fill()
+
+	for i, e := range entries {
+		top := y + legendPadding + float64(i)*legendLineH
+		dc.SetColor(e.Color)
+		dc.DrawRectangle(x+legendPadding, top+(legendLineH-legendSwatch)/2, legendSwatch, legendSwatch)
+		dc.Fill()
+
+		dc.SetColor(t.Text)
+		dc.DrawStringAnchored(e.Label, x+legendPadding+legendSwatch+4, top+legendLineH/2, 0, 0.35)
+	}
+}
+
+// drawDistanceLegend overlays the distance gradient scale in the bottom-left
+// corner of img.
+func drawDistanceLegend(img image.Image, minKm, maxKm float64, stack cornerStack, t theme) image.Image {
+	dc := newOverlay(img)
+
+	boxW, boxH := distanceLegendSize()
+	imgW, imgH := logicalSize(dc)
+	x, y := stack.place(BottomLeft, boxW, boxH, imgW, imgH)
+	drawDistanceLegendAt(dc, x, y, minKm, maxKm, t)
+
+	return dc.Image()
+}
+
+// legendImage draws the legends of a run on their own image: the group
+// swatches, then the distance gradient when distance is true. It returns
+// nil when there is nothing to show.
+func legendImage(entries []legendEntry, distance bool, minKm, maxKm float64, t theme) image.Image {
+	measure := useTextFont(gg.NewContext(1, 1))
+
+	w, h := 0.0, 0.0
+	if len(entries) > 0 {
+		w, h = groupLegendSize(measure, entries)
+	}
+	if distance {
+		dw, dh := distanceLegendSize()
+		if dw > w {
+			w = dw
+		}
+		h += dh
+	}
+	if w == 0 {
+		return nil
+	}
+
+	dc := useTextFont(gg.NewContext(int(w*pixelScale+0.5), int(h*pixelScale+0.5)))
+	dc.Scale(pixelScale, pixelScale)
+	dc.SetColor(t.Panel)
+	dc.Clear()
+
+	y := 0.0
+	if len(entries) > 0 {
+		drawGroupLegendAt(dc, 0, 0, entries, t)
+		_, y = groupLegendSize(dc, entries)
+	}
+	if distance {
+		drawDistanceLegendAt(dc, 0, y, minKm, maxKm, t)
+	}
+
+	return dc.Image()
+}
+
+// drawEntryLegend overlays swatches in the top-right corner of img.
+func drawEntryLegend(img image.Image, entries []legendEntry, stack cornerStack, t theme) image.Image {
+	dc := newOverlay(img)
+
+	boxW, boxH := groupLegendSize(dc, entries)
+	imgW, imgH := logicalSize(dc)
+	x, y := stack.place(TopRight, boxW, boxH, imgW, imgH)
+	drawGroupLegendAt(dc, x, y, entries, t)
+
+	return dc.Image()
+}
@@ -2,17 +2,22 @@ package main
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"image"
 	"image/color"
+	"image/draw"
 	"io"
+	"math"
 	"os"
-	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
 	sm "github.com/flopp/go-staticmaps"
 	"github.com/fogleman/gg"
@@ -34,141 +39,2015 @@ var (
 	ErrLatLong           = errors.New("LatLong is incorrect")
 	ErrLatLongOutOfRange = errors.New("LatLong is out of range")
 	ErrBadInput          = errors.New("Bad input")
+	ErrTooFewRows        = errors.New("Too few valid rows")
 )
 
+// options holds the command line configuration for a run.
+type options struct {
+	filename      string
+	mode          string
+	format        string
+	imageFormat   string // format of rendered images, from -format
+	quality       int
+	limit         int
+	limitPerGroup int
+	groupCol      int
+	verbose       bool
+	distanceModel string
+
+	colorByDistance bool
+	distanceMin     float64 // km
+	distanceMax     float64 // km
+
+	baseImage string
+
+	srcCol     int
+	dstCol     int
+	roleCol    int
+	roles      roleValues
+	noPrecheck bool
+	maxMarkers int
+	coordType  string
+	coordUnit  string
+
+	markerSize  float64
+	markerStyle string // MarkerPin or MarkerCircle, empty for the mode's own
+	roleStyle   string // RoleFillHollow, empty draws both ends alike
+	sizeCol     int
+	sizeMin     float64
+	sizeMax     float64
+
+	geocoder      Geocoder
+	srcGeocodeCol int
+	dstGeocodeCol int
+
+	fixedZoom  int
+	zoomLevels []int // zooms mode
+	minRows    int
+
+	origin *s2.LatLng
+
+	nameByHash bool
+	force      bool
+
+	waypointsCol int
+
+	markerOutline      string
+	markerOutlineWidth float64
+
+	transform      coordTransform
+	cellTransforms cellTransforms
+
+	labelCol   int
+	popupCols  []int
+	exportCols []int
+
+	wrap bool
+
+	heatmapCell float64
+	heatmapCSV  string
+
+	groupColors map[string]color.RGBA
+	fileColors  map[string]color.RGBA
+
+	jitter float64
+	seed   int64
+
+	center    *s2.LatLng
+	noBasemap bool
+
+	clipToView bool
+
+	comment    rune
+	delimiter  rune
+	lazyQuotes bool
+
+	sortCol  int
+	sortDesc bool
+
+	glyphCol int
+
+	theme theme
+
+	dash   []float64
+	stroke lineStroke
+
+	headerRows int
+
+	legendOut string
+
+	skipExisting bool
+	merge        bool
+
+	mask      mask
+	districts []namedRegion // choropleth mode
+
+	diffBase string // file A of diff mode
+
+	changedSince string // GeoJSON of an earlier run
+
+	focusPercentile float64
+
+	nameTemplate *template.Template
+
+	northArrow string // corner, empty disables
+	statsBox   string // corner, empty disables
+	countOnly  bool
+	grayscale  bool
+	spillAfter int // markers kept in memory under -spill, 0 keeps all
+
+	inset     string // corner, empty disables
+	insetSize int
+
+	midpoints bool
+
+	idCol   int
+	timeCol int
+
+	freqSize bool
+
+	transportCol    int
+	transportStyles map[string]transportStyle
+
+	noOrderDetect bool
+	order         *orderDetector // per file, see markLocations
+
+	bufferKm    float64
+	bufferAlpha float64
+
+	byteStart int64
+	byteEnd   int64
+
+	centroid      string
+	centroidLabel bool
+	ellipseSigma  float64 // -std-ellipse, 0 disables
+
+	vectorExport bool
+	drawIDs      bool
+	hopLabels    bool
+
+	tiles         string
+	fallbackTiles string
+	retries       int
+
+	regionCol int
+	ranges    rangeFilters
+	regions   regionFilter
+
+	flipX bool // diagnostic
+	flipY bool // diagnostic
+
+	topN      int
+	precision precision
+
+	approxQuantiles bool
+
+	snapGrid grid
+
+	arcStyle        string
+	arcCurvature    float64
+	widthByDistance bool
+	widthMin        float64
+	widthMax        float64
+
+	opener    sourceOpener
+	diag      *json.Encoder
+	errorRows *errorRows
+	hasher    *contentHasher // -audit-log, hashes the input as it's read
+
+	contactSheet string
+	manifest     string
+	report       string
+	sheetColumns int
+	sheetOnly    bool
+
+	outDir string
+	output string // -o, the PNG path for a single input
+	safe   bool
+}
+
+// summary collects the counts and totals of a run.
+type summary struct {
+	RowCount      int
+	Routes        int
+	Skipped       int
+	TotalDistance float64 // meters
+
+	// Outside counts rows dropped by -mask
+	Outside int
+
+	// Centroid is set by -centroid
+	Centroid *s2.LatLng
+
+	// Ellipse is set by -std-ellipse
+	Ellipse *stdEllipse
+
+	// Stats collects the route distances in stats mode and for -report
+	Stats *distanceStats
+
+	// LeftOut counts the rows not plotted per reason, for -report
+	LeftOut map[string]int
+}
+
+// groupPalette colors groups; see paletteColor.
+var groupPalette = []color.RGBA{
+	{0xe4, 0x1a, 0x1c, 0xff},
+	{0x37, 0x7e, 0xb8, 0xff},
+	{0x4d, 0xaf, 0x4a, 0xff},
+	{0x98, 0x4e, 0xa3, 0xff},
+	{0xff, 0x7f, 0x00, 0xff},
+	{0xa6, 0x56, 0x28, 0xff},
+	{0xf7, 0x81, 0xbf, 0xff},
+	{0x99, 0x99, 0x99, 0xff},
+}
+
 func main() {
 
-	var limit int
-	var filename, mode string
+	var opts options
+	flag.Usage = usage
+
+	flag.StringVar(&opts.mode, "mode", "plot", "a string var")
+	flag.StringVar(&opts.filename, "file", "", "a string var")
+	data := flag.String("data", "", "inline CSV content instead of -file; \\n separates rows")
+	flag.StringVar(&opts.format, "format", "", "output format; extent, stats and topsources modes: text|json; rendering modes: png|webp")
+	flag.BoolVar(&opts.approxQuantiles, "approx-quantiles", false, "stats mode: estimate distance quantiles in bounded memory (P²), compared with the exact values up to 100000 routes")
+	flag.IntVar(&opts.quality, "quality", 80, "-format webp quality, 1-100 (100 is lossless)")
+	flag.IntVar(&opts.topN, "n", 10, "number of locations listed by topsources mode (0 lists all)")
+	snapGrid := flag.String("snap-grid", "", "snap every coordinate to the center of its grid cell, in degrees or meters (500m), to coarsen exact locations")
+	prec := flag.String("precision", "5", "how close coordinates group as one in topsources and diff modes: decimals (5 is about 1 m) or meters, e.g. 50m")
+	flag.IntVar(&opts.limit, "limit", 0, "an int var")
+	flag.IntVar(&opts.groupCol, "group-col", -1, "column index to group and color markers by (-1 disables)")
+	flag.IntVar(&opts.limitPerGroup, "limit-per-group", 0, "max markers plotted per group value (0 is unlimited)")
+	flag.BoolVar(&opts.verbose, "verbose", false, "print per-row and per-group details")
+	flag.StringVar(&opts.distanceModel, "distance-model", DistanceSpherical, "distance model: spherical|ellipsoid")
+	flag.BoolVar(&opts.colorByDistance, "color-by-distance", false, "in line mode, color routes from green (short) to red (long)")
+	flag.Float64Var(&opts.distanceMin, "distance-min", 0, "distance in km mapped to the start of the gradient")
+	flag.Float64Var(&opts.distanceMax, "distance-max", 1000, "distance in km mapped to the end of the gradient")
+	flag.IntVar(&opts.srcCol, "src-col", 9, "column index of the source coordinates")
+	flag.IntVar(&opts.dstCol, "dst-col", 12, "column index of the destination coordinates")
+	flag.IntVar(&opts.roleCol, "role-col", -1, "long format input: column telling a route's source row from its destination row, paired by -id-col, each located by -src-col (-1 disables)")
+	roleValues := flag.String("role-values", DefaultRoles, "the -role-col values of source and destination rows, comma separated")
+	flag.BoolVar(&opts.noPrecheck, "no-precheck", false, "don't fail when none of the first data rows have valid coordinates")
+	flag.IntVar(&opts.maxMarkers, "max-markers", 100000, "stop adding markers beyond this many (0 disables the cap)")
+	flag.StringVar(&opts.coordType, "coord-type", CoordLatLng, "coordinate cell format: latlng|geohash")
+	flag.StringVar(&opts.coordUnit, "coord-unit", UnitDegrees, "unit of latlng cells: degrees|radians, converted to degrees before the range check")
+	flag.Float64Var(&opts.markerSize, "marker-size", 4.0, "marker size in pixels")
+	flag.BoolVar(&opts.clipToView, "clip-to-view", false, "drop markers outside the rendered image before drawing and print how many; only -center, -fixed-zoom, -focus-percentile or -base-image views leave markers outside")
+	flag.StringVar(&opts.markerStyle, "marker-style", "", "pin (tip on the coordinate) or circle (centered on it); images default to pins, html mode to circles")
+	flag.StringVar(&opts.roleStyle, "role-style", "", "fill-hollow draws sources as rings and destinations as filled circles, telling them apart without color (empty disables)")
+	flag.IntVar(&opts.sizeCol, "size-col", -1, "numeric column scaling marker size between -size-min and -size-max (-1 disables; ignored with -freq-size, whose repeat counts win)")
+	flag.Float64Var(&opts.sizeMin, "size-min", 2.0, "marker size for the smallest -size-col value")
+	flag.Float64Var(&opts.sizeMax, "size-max", 16.0, "marker size for the largest -size-col value")
+	geocode := flag.Bool("geocode", false, "geocode empty coordinates from -src-geocode-col/-dst-geocode-col via Nominatim")
+	flag.IntVar(&opts.srcGeocodeCol, "src-geocode-col", -1, "address or postal code column used when the source coordinates are empty")
+	flag.IntVar(&opts.dstGeocodeCol, "dst-geocode-col", -1, "address or postal code column used when the destination coordinates are empty")
+	flag.IntVar(&opts.fixedZoom, "fixed-zoom", 0, "render at this zoom level instead of auto-fitting (still centered on the data)")
+	zoomLevels := flag.String("zoom-levels", "", "comma separated zoom levels zooms mode renders an image at, e.g. 4,8,12")
+	flag.IntVar(&opts.minRows, "min-rows", 0, "fail unless at least this many valid coordinate pairs are parsed (0 disables)")
+	origin := flag.String("origin", "", "fixed \"lat,lng\" every route starts from; the source column is ignored")
+	flag.BoolVar(&opts.nameByHash, "name-by-hash", false, "name the output by a hash of the input and options, skipping the run if it exists")
+	flag.BoolVar(&opts.force, "force", false, "render even if the output already exists")
+	flag.IntVar(&opts.waypointsCol, "waypoints-col", -1, "column holding a \"lat,lng|lat,lng|...\" route drawn through every stop (replaces -src-col/-dst-col)")
+	themeName := flag.String("theme", "light", "bundled colors and tiles: "+themeNames())
+	srcColor := flag.String("src-color", "", "source marker color, overriding -theme")
+	dstColor := flag.String("dst-color", "", "destination marker color, overriding -theme")
+	lineColor := flag.String("line-color", "", "line color, overriding -theme")
+	background := flag.String("background", "", "fill color of -no-basemap renders, overriding -theme")
+	flag.StringVar(&opts.markerOutline, "marker-outline", "", "color of an outline drawn around each marker, e.g. white or #ffffff (empty disables)")
+	flag.Float64Var(&opts.markerOutlineWidth, "marker-outline-width", 1.5, "marker outline width in pixels")
+	transform := flag.String("transform", "", "convert input coordinates to WGS84 first, e.g. utm:43N for \"easting,northing\" cells")
+	transformCol := flag.String("transform-col", "", "\";\" separated COL=OP rewrites of raw cells before parsing; OP is swap_latlng, *K, /K, +K or -K, e.g. 9=*0.0000001")
+	flag.IntVar(&opts.labelCol, "label-col", -1, "column shown as each marker's label in html mode (-1 disables)")
+	popupCols := flag.String("popup-cols", "", "comma separated column indexes shown in html mode popups")
+	exportCols := flag.String("export-cols", "", "comma separated column indexes parquet mode writes along with the coordinates and distance")
+	noBounds := flag.Bool("no-bounds", false, "accept any valid latitude/longitude instead of the boundary points")
+	sentinelList := flag.String("sentinels", "-999,-999", "\";\" separated placeholder pairs treated as a missing location, checked before the range (empty disables)")
+	bbox := flag.String("bbox", "", "accepted \"minLat,minLng,maxLat,maxLng\" range, replacing the built-in boundary points")
+	flag.IntVar(&opts.transportCol, "transport-col", -1, "in line mode, column with the mode of transport styled by -transport-styles")
+	transportStyles := flag.String("transport-styles", "", "line look per -transport-col value as mode:color[:solid|dashed|dotted], e.g. bike:green,van:blue:dashed, or @file")
+	flag.BoolVar(&opts.noOrderDetect, "no-order-detect", false, "always read cells as lat,lng instead of switching to lng,lat when the first cell's latitude is beyond ±90")
+	flag.BoolVar(&opts.freqSize, "freq-size", false, "draw one marker per location, grouped by -precision, grown by the log of its repeat count")
+	flag.IntVar(&opts.timeCol, "time-col", -1, "trail mode: column ordering the scans of a shipment, numbers or sortable text such as RFC 3339 times (-1 keeps file order)")
+	flag.IntVar(&opts.idCol, "id-col", -1, "column holding a row identifier, shown with the row number in -verbose and -diag-out and used for marker IDs (-1 disables)")
+	flag.BoolVar(&opts.midpoints, "midpoints", false, "also draw a small orange marker at each route's great-circle midpoint")
+	flag.StringVar(&opts.inset, "inset", "", "draw an overview of the whole -bbox extent, framing the main map, in this corner (empty disables)")
+	scale := flag.Int("scale", 1, "pixel density: 2 or 4 render a @2x or @4x image of the same view, markers, lines and text scaled along")
+	flag.IntVar(&opts.insetSize, "inset-size", 150, "inset width in pixels; the height follows the map's aspect ratio")
+	flag.StringVar(&opts.statsBox, "stats-box", "", "draw the plotted and skipped counts and distances in this corner: top-left, top-right, bottom-left or bottom-right (empty disables)")
+	flag.BoolVar(&opts.countOnly, "count-only", false, "only print the number of valid routes per input, exiting 1 when one has none; nothing is drawn or written")
+	flag.BoolVar(&opts.grayscale, "grayscale", false, "desaturate the basemap tiles so the colored markers and lines stand out")
+	spill := flag.Bool("spill", false, "in plot and line modes, keep markers beyond -spill-after in a temporary file instead of memory, for inputs too large to hold")
+	spillAfter := flag.Int("spill-after", DefaultSpillAfter, "number of markers -spill keeps in memory before writing the rest to disk")
+	fontPath := flag.String("font", "", "TTF file text overlays are drawn in, for labels outside Latin script; a common system font when empty")
+	flag.StringVar(&opts.northArrow, "north-arrow", "", "draw a north arrow in this corner: top-left, top-right, bottom-left or bottom-right (empty disables)")
+	nameTemplate := flag.String("name-template", "", "Go template for image names with .Base .Mode .RowCount .Timestamp .Width .Height, e.g. \"{{.Base}}-{{.Mode}}-{{.Width}}x{{.Height}}\"")
+	flag.Float64Var(&opts.focusPercentile, "focus-percentile", 0, "frame the render on the densest -heatmap-cell cells holding this percent of the points, e.g. 90 (0 frames all points)")
+	maskFile := flag.String("mask", "", "GeoJSON file of Polygon/MultiPolygon regions; only routes with every point inside one are plotted")
+	flag.BoolVar(&opts.merge, "merge", false, "overlay every input file on one map instead of one output per file")
+	flag.BoolVar(&opts.skipExisting, "skip-existing", false, "name outputs by -name-by-hash and skip inputs whose output already exists, for resumable batches (-force re-renders)")
+	flag.StringVar(&opts.legendOut, "legend-out", "", "write the group and distance legend to this PNG instead of overlaying it on the map")
+	flag.Float64Var(&opts.bufferKm, "buffer-km", 0, "draw a catchment circle of this radius in km around each source (0 disables)")
+	flag.Float64Var(&opts.bufferAlpha, "buffer-alpha", 0.2, "fill opacity of -buffer-km circles, 0 to 1")
+	flag.IntVar(&opts.headerRows, "header-rows", 1, "number of header rows before the data; the last one names the columns")
+	flag.Int64Var(&opts.byteStart, "byte-start", 0, "read only lines starting at or after this byte offset, plus the header, for sharded runs")
+	flag.Int64Var(&opts.byteEnd, "byte-end", 0, "read only lines starting before this byte offset (0 reads to the end)")
+	flag.StringVar(&opts.centroid, "centroid", "", "draw the center of all sources: mean or median (geometric median, less pulled by outliers); empty disables")
+	flag.BoolVar(&opts.centroidLabel, "centroid-label", false, "label the -centroid marker with its coordinates")
+	stdEllipseOn := flag.Bool("std-ellipse", false, "draw the standard deviational ellipse of the sources around their mean center and print its center, axes and rotation")
+	ellipseSigma := flag.Float64("ellipse-sigma", 1, "standard deviations the -std-ellipse axes span, e.g. 1 or 2")
+	lineCap := flag.String("line-cap", "round", "how route ends are drawn: round, butt or square")
+	lineJoin := flag.String("line-join", "round", "how route corners, e.g. at waypoints, are drawn: round or bevel")
+	dash := flag.String("dash", "", "in line mode, dash pattern of the routes as comma separated pixel lengths, e.g. 5,3 (empty draws solid lines)")
+	flag.BoolVar(&opts.vectorExport, "geojson", false, "also write the plotted markers and lines as GeoJSON next to the image, each marker with its ID")
+	flag.BoolVar(&opts.drawIDs, "draw-ids", false, "draw each marker's ID next to it")
+	flag.BoolVar(&opts.hopLabels, "hop-labels", false, "label each -waypoints-col route of more than one hop with its hop count at its first stop")
+	flag.StringVar(&opts.tiles, "tiles", "", "go-staticmaps tile provider, e.g. osm or carto-light (empty uses the -theme or library default)")
+	flag.StringVar(&opts.fallbackTiles, "fallback-tiles", "", "tile provider to retry the render with when -tiles fails")
+	flag.IntVar(&opts.regionCol, "region-col", -1, "column holding an ISO country or state code, for -allow-regions/-deny-regions")
+	flag.Var(&opts.ranges, "range", "keep rows whose numeric column is within COL=MIN:MAX, inclusive, either end may be left open, e.g. 7=1:5 or 7=10:; repeatable, rows must be within all")
+	allowRegions := flag.String("allow-regions", "", "comma separated region codes to keep, e.g. IN,NP (needs -region-col)")
+	denyRegions := flag.String("deny-regions", "", "comma separated region codes to drop, e.g. IN-KA (needs -region-col)")
+	flag.BoolVar(&opts.flipX, "flip-x", false, "diagnostic: mirror the final image left to right")
+	flag.BoolVar(&opts.flipY, "flip-y", false, "diagnostic: mirror the final image top to bottom")
+	geodesic := flag.Bool("geodesic", false, "in line mode, draw routes as great-circle arcs instead of straight lines; same as -arc-style great-circle")
+	flag.StringVar(&opts.arcStyle, "arc-style", "", "in line mode, draw routes as great-circle (geodesic) or bezier (curves bowed north, the flight-map look) arcs; empty draws straight lines")
+	flag.Float64Var(&opts.arcCurvature, "arc-curvature", 0.2, "-arc-style bezier: how far the curve bows out, as a fraction of the route length")
+	flag.BoolVar(&opts.widthByDistance, "width-by-distance", false, "in line mode, scale line width with distance over -distance-min/-distance-max")
+	flag.Float64Var(&opts.widthMin, "width-min", 1, "line width in pixels of the shortest routes with -width-by-distance, and of single route edges in flows mode")
+	flag.Float64Var(&opts.widthMax, "width-max", 6, "line width in pixels of the longest routes with -width-by-distance, and of the heaviest edge in flows mode")
+	flag.BoolVar(&opts.wrap, "wrap", false, "split routes crossing the antimeridian so they take the short way around")
+	flag.Float64Var(&opts.heatmapCell, "heatmap-cell", 0.1, "heatmap mode grid cell size in degrees")
+	flag.StringVar(&opts.heatmapCSV, "heatmap-csv", "", "heatmap mode: also write the per-cell counts to this CSV file")
+	fileColors := flag.String("file-colors", "", "color each input's routes, \"file.csv:#hex,...\" or @file; other inputs get a color hashed from their name")
+	groupColors := flag.String("group-colors", "", "pin group colors, \"key:#hex,...\" or @file; other groups get a color hashed from their value")
+	flag.Float64Var(&opts.jitter, "jitter", 0, "spread markers sharing a position by up to this many pixels (0 disables)")
+	flag.Int64Var(&opts.seed, "seed", 1, "seed for -jitter offsets; the same seed and input give the same offsets")
+	flag.StringVar(&opts.changedSince, "changed-since", "", "GeoJSON written by an earlier -geojson run: draw only the markers new (source color) or moved (orange) since then")
+	flag.StringVar(&opts.report, "report", "", "also write a Markdown summary of the run: changed flags, row counts, why rows were left out, distance stats and a link to each output")
+	flag.StringVar(&opts.manifest, "manifest", "", "also write a JSON index of every output, numbered in input order with its parameters and row counts")
+	flag.StringVar(&opts.contactSheet, "contact-sheet", "", "also tile every rendered file into this PNG, captioned with file name and row count")
+	flag.IntVar(&opts.sheetColumns, "columns", 3, "contact sheet columns")
+	flag.BoolVar(&opts.sheetOnly, "sheet-only", false, "with -contact-sheet, don't save the individual renders")
+	flag.StringVar(&opts.outDir, "outdir", ImagesDir, "directory outputs are written to")
+	flag.StringVar(&opts.output, "o", "", "write the PNG to this path instead of a generated name in -outdir (one input only)")
+	flag.BoolVar(&opts.safe, "safe", false, "reject output paths that resolve outside -outdir, for names that come from untrusted requests")
+	flag.IntVar(&opts.retries, "retries", 0, "retry a failed render this many times, waiting 1s, 2s, 4s and so on up to 30s in between")
+	deadlineFlag := flag.Duration("deadline", 0, "stop reading and rendering after this long, e.g. 5m, write partial output and exit with status 124 (0 disables)")
+	diagOut := flag.String("diag-out", "", "write one NDJSON line per input row saying whether it was plotted or skipped and why")
+	auditLog := flag.String("audit-log", "", "append one JSON line per run to this file: time, status, the flags set, and each input's SHA-256, row counts and output")
+	errorsOut := flag.String("errors-out", "", "write the skipped rows verbatim to this CSV, with the input's header and delimiter and a skip_reason column, to fix and re-run")
+	center := flag.String("center", "", "\"lat,lng\" map center instead of centering on the data")
+	flag.BoolVar(&opts.noBasemap, "no-basemap", false, "draw markers and lines on a transparent PNG without tiles (needs -center and -fixed-zoom)")
+	decimalSep := flag.String("decimal-sep", DecimalPoint, "decimal separator of the input coordinates: . or , (then cells hold \"lat;lng\" or \"lat lng\" and -delimiter defaults to ;)")
+	delimiter := flag.String("delimiter", "", "CSV field delimiter (default , or ; with -decimal-sep ,)")
+	comment := flag.String("comment", "#", "character starting comment lines to skip (empty disables)")
+	flag.BoolVar(&opts.lazyQuotes, "lazy-quotes", false, "tolerate stray double quotes inside fields instead of skipping the row")
+	flag.IntVar(&opts.sortCol, "sort-by-col", -1, "draw routes ordered by this column, last drawn on top (-1 keeps file order)")
+	flag.BoolVar(&opts.sortDesc, "sort-desc", false, "sort -sort-by-col descending")
+	flag.IntVar(&opts.glyphCol, "glyph-col", -1, "draw markers as the first character of this column on the marker color (-1 disables)")
+	flag.StringVar(&opts.baseImage, "base-image", "", "previously rendered PNG to draw the markers onto (needs its .json sidecar)")
+
+	var explain explainFlag
+	flag.Var(&explain, "explain", "print every flag's effective value and whether it came from the command line, the input's #courierinfo: directive or the default, then run; -explain=json for JSON")
+
+	flag.Parse()
+	handleInterrupts()
+	if *deadlineFlag > 0 {
+		startDeadline(*deadlineFlag)
+	}
+	if opts.retries < 0 {
+		terminate(fmt.Errorf("%w: -retries must not be negative", ErrBadInput))
+	}
+
+	files, err := expandInputs(opts.filename, flag.Args())
+	if err != nil {
+		terminate(err)
+	}
+
+	if *data != "" {
+		if len(files) > 0 {
+			terminate(fmt.Errorf("%w: -data and -file are mutually exclusive", ErrBadInput))
+		}
+		opts.opener = newDataOpener(*data)
+		files = []string{InlineSource}
+	}
+
+	if len(files) > 0 {
+		if err := applyDirective(files[0], opts); err != nil {
+			terminate(err)
+		}
+	}
+
+	if explain != "" {
+		if err := writeExplain(os.Stdout, string(explain)); err != nil {
+			terminate(err)
+		}
+	}
+
+	if opts.distanceModel != DistanceSpherical && opts.distanceModel != DistanceEllipsoid {
+		terminate(ErrBadInput)
+	}
+
+	if opts.coordType != CoordLatLng && opts.coordType != CoordGeohash {
+		terminate(ErrBadInput)
+	}
+
+	if opts.coordUnit != UnitDegrees && opts.coordUnit != UnitRadians {
+		terminate(fmt.Errorf("%w: -coord-unit %q, expected %s or %s", ErrBadInput, opts.coordUnit, UnitDegrees, UnitRadians))
+	}
+	if opts.coordUnit == UnitRadians && (opts.coordType != CoordLatLng || *transform != "") {
+		terminate(fmt.Errorf("%w: -coord-unit radians applies to latlng cells, not -coord-type %s or -transform", ErrBadInput, opts.coordType))
+	}
+
+	switch utf8.RuneCountInString(*comment) {
+	case 0:
+	case 1:
+		opts.comment, _ = utf8.DecodeRuneInString(*comment)
+	default:
+		terminate(fmt.Errorf("%w: -comment %q must be a single character", ErrBadInput, *comment))
+	}
+
+	if *decimalSep != DecimalPoint && *decimalSep != DecimalComma {
+		terminate(fmt.Errorf("%w: -decimal-sep %q, expected . or ,", ErrBadInput, *decimalSep))
+	}
+	opts.delimiter = ','
+	if *decimalSep == DecimalComma {
+		opts.delimiter = ';'
+	}
+	if *delimiter != "" {
+		r, size := utf8.DecodeRuneInString(*delimiter)
+		if size != len(*delimiter) || r == '"' || r == '\r' || r == '\n' || r == opts.comment {
+			terminate(fmt.Errorf("%w: -delimiter %q must be a single character other than a quote, newline or -comment", ErrBadInput, *delimiter))
+		}
+		opts.delimiter = r
+	}
+
+	if *popupCols != "" {
+		cols, err := parseIntList(*popupCols)
+		if err != nil {
+			terminate(err)
+		}
+		opts.popupCols = cols
+	}
+
+	if *exportCols != "" {
+		cols, err := parseIntList(*exportCols)
+		if err != nil {
+			terminate(err)
+		}
+		opts.exportCols = cols
+	}
+
+	if *groupColors != "" {
+		mapping, err := parseColorMapping(*groupColors)
+		if err != nil {
+			terminate(err)
+		}
+		opts.groupColors = mapping
+	}
+
+	if *bbox != "" {
+		b, err := parseBoundingBox(*bbox)
+		if err != nil {
+			terminate(err)
+		}
+		activeBounds = b
+	}
+	if *noBounds {
+		if *bbox != "" {
+			terminate(fmt.Errorf("%w: -no-bounds and -bbox are mutually exclusive", ErrBadInput))
+		}
+		activeBounds = boundingBox{MinLat: -90, MinLng: -180, MaxLat: 90, MaxLng: 180}
+	}
+
+	s, err := parseSentinels(*sentinelList)
+	if err != nil {
+		terminate(err)
+	}
+	sentinels = s
+
+	for _, name := range []string{opts.tiles, opts.fallbackTiles} {
+		if _, err := lookupTileProvider(name); err != nil {
+			terminate(err)
+		}
+	}
+
+	if opts.bufferKm < 0 || opts.bufferAlpha < 0 || opts.bufferAlpha > 1 {
+		terminate(fmt.Errorf("%w: -buffer-km must not be negative and -buffer-alpha must be between 0 and 1", ErrBadInput))
+	}
+
+	if opts.headerRows < 0 {
+		terminate(fmt.Errorf("%w: -header-rows must not be negative", ErrBadInput))
+	}
+
+	if opts.byteStart < 0 || opts.byteEnd < 0 || (opts.byteEnd > 0 && opts.byteEnd <= opts.byteStart) {
+		terminate(fmt.Errorf("%w: -byte-start and -byte-end must be positive with -byte-start below -byte-end", ErrBadInput))
+	}
+
+	if *stdEllipseOn {
+		if *ellipseSigma <= 0 {
+			terminate(fmt.Errorf("%w: -ellipse-sigma must be positive", ErrBadInput))
+		}
+		opts.ellipseSigma = *ellipseSigma
+	}
+
+	if opts.centroid != "" && opts.centroid != CentroidMean && opts.centroid != CentroidMedian {
+		terminate(fmt.Errorf("%w: -centroid %q, expected %s or %s", ErrBadInput, opts.centroid, CentroidMean, CentroidMedian))
+	}
+
+	if opts.skipExisting {
+		opts.nameByHash = true
+	}
+	if opts.nameByHash {
+		for _, file := range files {
+			if file == StdinSource {
+				terminate(fmt.Errorf("%w: -name-by-hash needs the whole input before reading it, which standard input can't give", ErrBadInput))
+			}
+		}
+	}
+
+	if *maskFile != "" {
+		opts.mask, err = loadMask(*maskFile)
+		if err != nil {
+			terminate(err)
+		}
+	}
+
+	if opts.mode == "choropleth" {
+		if *maskFile == "" {
+			terminate(fmt.Errorf("%w: choropleth mode counts points per -mask region, set -mask", ErrBadInput))
+		}
+		opts.districts, err = loadRegions(*maskFile)
+		if err != nil {
+			terminate(err)
+		}
+	}
+
+	if *dash != "" {
+		pattern, err := parseDash(*dash)
+		if err != nil {
+			terminate(err)
+		}
+		opts.dash = pattern
+	}
+
+	if opts.markerStyle != "" && opts.markerStyle != MarkerPin && opts.markerStyle != MarkerCircle {
+		terminate(fmt.Errorf("%w: -marker-style %q, expected %s or %s", ErrBadInput, opts.markerStyle, MarkerPin, MarkerCircle))
+	}
+
+	if opts.roleStyle != "" {
+		if err := checkRoleStyle(opts.roleStyle, opts.markerStyle); err != nil {
+			terminate(err)
+		}
+		opts.markerStyle = MarkerCircle
+	}
+
+	opts.stroke, err = parseStroke(*lineCap, *lineJoin)
+	if err != nil {
+		terminate(err)
+	}
+
+	opts.regions = regionFilter{allow: parseRegionList(*allowRegions), deny: parseRegionList(*denyRegions)}
+	if opts.regions.active() && opts.regionCol < 0 {
+		terminate(fmt.Errorf("%w: -allow-regions and -deny-regions need -region-col", ErrBadInput))
+	}
+
+	if *transportStyles != "" {
+		opts.transportStyles, err = parseTransportStyles(*transportStyles)
+		if err != nil {
+			terminate(err)
+		}
+	}
+	if opts.transportCol >= 0 && len(opts.transportStyles) == 0 {
+		terminate(fmt.Errorf("%w: -transport-col needs -transport-styles", ErrBadInput))
+	}
+
+	if opts.northArrow != "" {
+		if err := checkCorner(opts.northArrow); err != nil {
+			terminate(err)
+		}
+	}
+
+	if opts.statsBox != "" {
+		if err := checkCorner(opts.statsBox); err != nil {
+			terminate(err)
+		}
+	}
+
+	if err := checkScale(*scale); err != nil {
+		terminate(err)
+	}
+	pixelScale = float64(*scale)
+
+	loadTextFont(*fontPath)
+
+	if opts.inset != "" {
+		if err := checkCorner(opts.inset); err != nil {
+			terminate(err)
+		}
+		if opts.insetSize <= 0 || opts.insetSize > MapWidth/2 {
+			terminate(fmt.Errorf("%w: -inset-size must be between 1 and %d", ErrBadInput, MapWidth/2))
+		}
+		if opts.noBasemap {
+			terminate(fmt.Errorf("%w: -inset needs map tiles, it can't be used with -no-basemap", ErrBadInput))
+		}
+	}
+
+	if *nameTemplate != "" {
+		opts.nameTemplate, err = parseNameTemplate(*nameTemplate)
+		if err != nil {
+			terminate(err)
+		}
+	}
+
+	if opts.focusPercentile < 0 || opts.focusPercentile > 100 {
+		terminate(fmt.Errorf("%w: -focus-percentile must be between 0 and 100", ErrBadInput))
+	}
+
+	opts.precision, err = parsePrecision(*prec)
+	if err != nil {
+		terminate(err)
+	}
+
+	switch opts.mode {
+	case "parquet":
+		if !ParquetAvailable {
+			terminate(ErrNoParquet)
+		}
+	case "extent", "stats", "topsources", "html", "midpoints", "pixels":
+	default:
+		opts.imageFormat, err = imageFormat(opts.format)
+		if err != nil {
+			terminate(err)
+		}
+		if opts.imageFormat == FormatWebP && !WebPAvailable {
+			terminate(ErrNoWebP)
+		}
+		if opts.quality < 1 || opts.quality > 100 {
+			terminate(fmt.Errorf("%w: -quality must be between 1 and 100", ErrBadInput))
+		}
+	}
+	// the hashed name is an image name, which the other modes never write
+	if opts.nameByHash && opts.imageFormat == "" {
+		terminate(fmt.Errorf("%w: -name-by-hash and -skip-existing only name image outputs, not %s mode", ErrBadInput, opts.mode))
+	}
+
+	opts.snapGrid, err = parseGrid(*snapGrid)
+	if err != nil {
+		terminate(err)
+	}
+
+	if *geodesic {
+		if opts.arcStyle != "" && opts.arcStyle != ArcGreatCircle {
+			terminate(fmt.Errorf("%w: -geodesic conflicts with -arc-style %s", ErrBadInput, opts.arcStyle))
+		}
+		opts.arcStyle = ArcGreatCircle
+	}
+	if err := checkArcStyle(opts.arcStyle); err != nil {
+		terminate(err)
+	}
+	if opts.arcCurvature < 0 || opts.arcCurvature > 1 {
+		terminate(fmt.Errorf("%w: -arc-curvature must be between 0 and 1", ErrBadInput))
+	}
+
+	if (opts.widthByDistance || opts.mode == "flows") && (opts.widthMin <= 0 || opts.widthMin > opts.widthMax) {
+		terminate(fmt.Errorf("%w: -width-min must be positive and at most -width-max", ErrBadInput))
+	}
+
+	opts.theme, err = parseTheme(*themeName, *srcColor, *dstColor, *lineColor, *background)
+	if err != nil {
+		terminate(err)
+	}
+
+	if *transform != "" {
+		t, err := parseTransform(*transform)
+		if err != nil {
+			terminate(err)
+		}
+		opts.transform = t
+	}
+
+	if *transformCol != "" {
+		opts.cellTransforms, err = parseCellTransforms(*transformCol)
+		if err != nil {
+			terminate(err)
+		}
+	}
+
+	if *origin != "" {
+		x, y, err := getLatLong(*origin)
+		if err != nil {
+			terminate(fmt.Errorf("%w: -origin %q: %v", ErrBadInput, *origin, err))
+		}
+		ll := s2.LatLngFromDegrees(x, y)
+		opts.origin = &ll
+	}
+
+	if *center != "" {
+		x, y, err := parsePair(*center)
+		if err != nil {
+			terminate(fmt.Errorf("%w: -center %q: %v", ErrBadInput, *center, err))
+		}
+		ll := s2.LatLngFromDegrees(x, y)
+		opts.center = &ll
+	}
+
+	if opts.noBasemap && (opts.center == nil || opts.fixedZoom <= 0) {
+		terminate(fmt.Errorf("%w: -no-basemap has no tiles to fit against, set -center and -fixed-zoom", ErrBadInput))
+	}
+
+	if *geocode {
+		opts.geocoder = newCachingGeocoder(newNominatim(NominatimURL))
+	}
+
+	if opts.safe {
+		for _, p := range []string{opts.output, opts.legendOut, opts.contactSheet, opts.manifest, opts.report, opts.heatmapCSV, *diagOut, *errorsOut, *auditLog} {
+			if p == "" {
+				continue
+			}
+			if err := checkWithin(p, opts.outDir); err != nil {
+				terminate(err)
+			}
+		}
+	}
+
+	if *diagOut != "" {
+		file, err := os.Create(*diagOut)
+		if err != nil {
+			terminate(err)
+		}
+		defer file.Close()
+		opts.diag = json.NewEncoder(file)
+	}
+
+	if *errorsOut != "" {
+		opts.errorRows = &errorRows{delimiter: opts.delimiter}
+	}
+
+	if len(files) == 0 {
+		terminate(ErrBadInput)
+	}
+
+	if *fileColors != "" {
+		if opts.groupCol >= 0 {
+			terminate(fmt.Errorf("%w: -file-colors and -group-col both color markers, use one", ErrBadInput))
+		}
+		mapping, err := parseColorMapping(*fileColors)
+		if err != nil {
+			terminate(err)
+		}
+		warnUnmatchedFileColors(mapping, files)
+		opts.fileColors = mapping
+	}
+
+	if opts.mode == "trail" && opts.idCol < 0 {
+		terminate(fmt.Errorf("%w: trail mode groups scans by shipment, set -id-col", ErrBadInput))
+	}
+	if opts.mode == "chain" && opts.idCol < 0 {
+		terminate(fmt.Errorf("%w: chain mode joins rows sharing a shipment ID, set -id-col", ErrBadInput))
+	}
+
+	if opts.roleCol >= 0 {
+		if opts.idCol < 0 {
+			terminate(fmt.Errorf("%w: -role-col pairs source and destination rows by ID, set -id-col", ErrBadInput))
+		}
+		if opts.waypointsCol >= 0 || opts.origin != nil {
+			terminate(fmt.Errorf("%w: -role-col reads both ends from rows, not -waypoints-col or -origin", ErrBadInput))
+		}
+		opts.roles, err = parseRoleValues(*roleValues)
+		if err != nil {
+			terminate(err)
+		}
+	}
+
+	if *spill {
+		if opts.mode != "plot" && opts.mode != "line" {
+			terminate(fmt.Errorf("%w: -spill works in plot and line modes, not %s", ErrBadInput, opts.mode))
+		}
+		if *spillAfter <= 0 {
+			terminate(fmt.Errorf("%w: -spill-after must be positive", ErrBadInput))
+		}
+		// these revisit every marker after the read, spilled ones only keep
+		// their position, color and size
+		for _, c := range []struct {
+			name string
+			set  bool
+		}{
+			{"-freq-size", opts.freqSize},
+			{"-jitter", opts.jitter > 0},
+			{"-clip-to-view", opts.clipToView},
+			{"-marker-outline", opts.markerOutline != ""},
+			{"-size-col", opts.sizeCol >= 0},
+			{"-glyph-col", opts.glyphCol >= 0},
+			{"-draw-ids", opts.drawIDs},
+			{"-geojson", opts.vectorExport},
+			{"-changed-since", opts.changedSince != ""},
+			{"-role-style", opts.roleStyle != ""},
+		} {
+			if c.set {
+				terminate(fmt.Errorf("%w: -spill can't be used with %s", ErrBadInput, c.name))
+			}
+		}
+		opts.spillAfter = *spillAfter
+
+		// the default -max-markers stops the read long before -spill-after
+		// is reached, so -spill lifts it unless it's given explicitly
+		maxMarkersSet := false
+		flag.Visit(func(f *flag.Flag) { maxMarkersSet = maxMarkersSet || f.Name == "max-markers" })
+		if !maxMarkersSet {
+			opts.maxMarkers = 0
+		}
+	}
+
+	if opts.mode == "zooms" {
+		opts.zoomLevels, err = parseZoomLevels(*zoomLevels)
+		if err != nil {
+			terminate(err)
+		}
+		if opts.fixedZoom > 0 || opts.baseImage != "" {
+			terminate(fmt.Errorf("%w: zooms mode sets the zoom of each image, it can't be used with -fixed-zoom or -base-image", ErrBadInput))
+		}
+		if opts.jitter > 0 || opts.nameByHash {
+			terminate(fmt.Errorf("%w: zooms mode can't be used with -jitter or -name-by-hash", ErrBadInput))
+		}
+	}
+
+	if opts.hopLabels && opts.waypointsCol < 0 {
+		terminate(fmt.Errorf("%w: -hop-labels counts the hops of -waypoints-col routes, set it too", ErrBadInput))
+	}
+
+	if opts.changedSince != "" && opts.mode == "diff" {
+		terminate(fmt.Errorf("%w: -changed-since and diff mode both compare against earlier data, use one", ErrBadInput))
+	}
+
+	if opts.mode == "diff" {
+		if len(files) != 2 {
+			terminate(fmt.Errorf("%w: diff mode takes two files, got %d", ErrBadInput, len(files)))
+		}
+		opts.diffBase = files[0]
+		files = files[1:]
+	}
+
+	if opts.merge && len(files) > 1 {
+		opts.opener = mergeOpener{files: files, headerRows: opts.headerRows, comment: opts.comment, delimiter: opts.delimiter, lazyQuotes: opts.lazyQuotes}
+		files = []string{MergedSource}
+	}
+
+	if opts.output != "" && len(files) > 1 {
+		terminate(fmt.Errorf("%w: -o names one output but there are %d inputs, use -outdir or -merge", ErrBadInput, len(files)))
+	}
+
+	// only the input uses comma decimals, the flags above have been parsed
+	decimalComma = *decimalSep == DecimalComma
+
+	if *auditLog != "" {
+		startAudit(*auditLog)
+	}
+
+	var results []*fileResult
+	existing := 0
+	for _, file := range files {
+		opts.filename = file
+		if *auditLog != "" {
+			opts.hasher = newContentHasher()
+		}
+
+		res, err := runFile(opts)
+		removeSpills()
+		addAudit(file, res, opts.hasher)
+		if err != nil {
+			terminate(err)
+		}
+		results = append(results, res)
+		if res.Skipped {
+			existing++
+		}
+
+		if isInterrupted() || deadlinePassed() {
+			break
+		}
+	}
+
+	if opts.verbose && opts.skipExisting {
+		fmt.Println(fmt.Sprintf("Skipped %d of %d files with existing outputs", existing, len(results)))
+	}
+
+	if opts.countOnly {
+		// one bare count, or "count file" lines as wc prints for several
+		empty := false
+		for i, res := range results {
+			if len(files) == 1 {
+				fmt.Println(res.Summary.Routes)
+			} else {
+				fmt.Println(fmt.Sprintf("%d %s", res.Summary.Routes, files[i]))
+			}
+			empty = empty || res.Summary.Routes == 0
+		}
+		if empty {
+			finishAudit(AuditFailed, fmt.Errorf("%w: an input has no valid routes", ErrTooFewRows))
+			os.Exit(1)
+		}
+	}
+
+	if opts.contactSheet != "" {
+		if err := writeContactSheet(opts.contactSheet, files, results, opts.sheetColumns); err != nil {
+			terminate(err)
+		}
+		fmt.Println("\nGenerated: ", opts.contactSheet)
+	}
+
+	if opts.report != "" {
+		if err := writeFile(opts.report, func(w io.Writer) error { return writeReport(w, opts.report, files, results, opts) }); err != nil {
+			terminate(err)
+		}
+		fmt.Println("\nGenerated: ", opts.report)
+	}
+
+	if opts.errorRows != nil {
+		if err := writeFile(*errorsOut, opts.errorRows.write); err != nil {
+			terminate(err)
+		}
+		fmt.Println(fmt.Sprintf("\nGenerated: %s (%d skipped rows)", *errorsOut, len(opts.errorRows.rows)))
+	}
+
+	if opts.manifest != "" {
+		if err := writeFile(opts.manifest, func(w io.Writer) error { return writeManifest(w, files, results) }); err != nil {
+			terminate(err)
+		}
+		fmt.Println("\nGenerated: ", opts.manifest)
+	}
+
+	if isInterrupted() {
+		finishAudit(AuditInterrupted, nil)
+		os.Exit(ExitInterrupted)
+	}
+	if deadlinePassed() {
+		fmt.Println(fmt.Sprintf("Error: %v, -deadline %s, the output is partial", ErrDeadline, *deadlineFlag))
+		finishAudit(AuditDeadline, ErrDeadline)
+		os.Exit(ExitDeadline)
+	}
+	finishAudit(AuditOK, nil)
+}
+
+// fileResult is the outcome of processing one input file.
+type fileResult struct {
+	Path    string      // written output, empty if none
+	Image   image.Image // rendered map, kept only for -contact-sheet
+	Summary *summary
+	Skipped bool // output was up to date
+
+	// Metadata is what the sidecar of an image output holds
+	Metadata *metadata
+}
+
+// runFile processes opts.filename according to the mode.
+func runFile(opts options) (*fileResult, error) {
+	if opts.countOnly {
+		_, sum, err := markLocations(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return &fileResult{Summary: sum}, nil
+	}
+
+	if opts.mode == "extent" {
+		lyr, sum, err := markLocations(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return &fileResult{Summary: sum}, writeExtent(os.Stdout, lyr, opts.format)
+	}
+
+	if opts.mode == "stats" {
+		_, sum, err := markLocations(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return &fileResult{Summary: sum}, writeStats(os.Stdout, sum.Stats, opts.format)
+	}
+
+	if opts.mode == "topsources" {
+		lyr, sum, err := markLocations(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return &fileResult{Summary: sum}, writeTopSources(os.Stdout, lyr.sources, opts.topN, opts.precision, opts.format)
+	}
+
+	fmt.Println(fmt.Sprintf("Input: %s, mode: %s, limit: %d", opts.filename, opts.mode, opts.limit))
+
+	baseName := sourceBaseName(opts.filename)
+
+	hashedPath := ""
+	if opts.nameByHash {
+		hash, err := inputHash(opts.filename, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		hashedPath = filepath.Join(opts.outDir, fmt.Sprintf("img-%s-%s-%s.%s", baseName, opts.mode, hash, opts.imageFormat))
+		if _, err := os.Stat(hashedPath); err == nil && !opts.force {
+			if opts.verbose {
+				fmt.Println(fmt.Sprintf("%s: skipped (exists)", opts.filename))
+			}
+			fmt.Println("\nUp to date, skipping: ", hashedPath)
+			return &fileResult{Path: hashedPath, Skipped: true}, nil
+		}
+	}
+	lyr, sum, err := markLocations(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.mode == "diff" {
+		lyr, err = diffLayer(lyr, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.changedSince != "" {
+		lyr, err = changedLayer(lyr, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if sum.Routes < opts.minRows {
+		return nil, fmt.Errorf("%w: %d parsed, -min-rows is %d", ErrTooFewRows, sum.Routes, opts.minRows)
+	}
+
+	if sum.Routes > 0 {
+		fmt.Println(fmt.Sprintf("Routes: %d, total distance: %.3f km, mean: %.3f km (%s)",
+			sum.Routes, sum.TotalDistance/1000, sum.TotalDistance/1000/float64(sum.Routes), opts.distanceModel))
+	}
+
+	if opts.mask != nil {
+		fmt.Println(fmt.Sprintf("Outside -mask: %d", sum.Outside))
+	}
+
+	if opts.snapGrid.enabled() {
+		fmt.Println(fmt.Sprintf("Snapped to %d distinct cells", distinctCells(markerPositions(lyr))))
+	}
+
+	if sum.Centroid != nil {
+		fmt.Println(fmt.Sprintf("Centroid (%s): %f,%f", opts.centroid, sum.Centroid.Lat.Degrees(), sum.Centroid.Lng.Degrees()))
+	}
+
+	if sum.Ellipse != nil {
+		fmt.Println(fmt.Sprintf("Standard ellipse: %v", sum.Ellipse))
+	} else if opts.ellipseSigma > 0 {
+		fmt.Println("Warning: -std-ellipse needs at least 3 sources, none drawn")
+	}
+
+	if opts.mode == "html" {
+		outFilePath, err := outputPath(fmt.Sprintf("map-%s-%d-%d.html", baseName, sum.RowCount, time.Now().Unix()), opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFile(outFilePath, func(w io.Writer) error { return writeHTML(w, lyr, baseName, opts.markerStyle == MarkerPin) }); err != nil {
+			return nil, err
+		}
+
+		fmt.Println("\nGenerated: ", outFilePath)
+		return &fileResult{Path: outFilePath, Summary: sum}, nil
+	}
+
+	if opts.mode == "midpoints" {
+		outFilePath, err := outputPath(fmt.Sprintf("midpoints-%s-%d-%d.csv", baseName, sum.RowCount, time.Now().Unix()), opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFile(outFilePath, func(w io.Writer) error { return writeMidpointCSV(w, lyr) }); err != nil {
+			return nil, err
+		}
+
+		fmt.Println("\nGenerated: ", outFilePath)
+		return &fileResult{Path: outFilePath, Summary: sum}, nil
+	}
+
+	if opts.mode == "parquet" {
+		outFilePath, err := outputPath(fmt.Sprintf("routes-%s-%d-%d.parquet", baseName, sum.RowCount, time.Now().Unix()), opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFile(outFilePath, func(w io.Writer) error { return writeParquet(w, lyr, opts) }); err != nil {
+			return nil, err
+		}
+
+		fmt.Println("\nGenerated: ", outFilePath)
+		return &fileResult{Path: outFilePath, Summary: sum}, nil
+	}
+
+	if opts.mode == "pixels" {
+		vp, err := frame(lyr, opts)
+		if err != nil {
+			return nil, err
+		}
+		if opts.jitter > 0 {
+			jitterMarkers(lyr.markers, vp, opts.jitter, opts.seed)
+		}
+
+		outFilePath, err := outputPath(fmt.Sprintf("pixels-%s-%d-%d.csv", baseName, sum.RowCount, time.Now().Unix()), opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFile(outFilePath, func(w io.Writer) error { return writePixelCSV(w, lyr, vp) }); err != nil {
+			return nil, err
+		}
+
+		fmt.Println(fmt.Sprintf("Pixels for %dx%d at zoom %d, center %f,%f",
+			vp.Width, vp.Height, vp.Zoom, vp.Center.Lat.Degrees(), vp.Center.Lng.Degrees()))
+		fmt.Println("\nGenerated: ", outFilePath)
+		return &fileResult{Path: outFilePath, Summary: sum}, nil
+	}
+
+	if opts.mode == "heatmap" {
+		if opts.heatmapCell <= 0 {
+			return nil, ErrBadInput
+		}
+
+		bins := binPoints(markerPositions(lyr), opts.heatmapCell)
+		if opts.heatmapCSV != "" {
+			if err := writeFile(opts.heatmapCSV, func(w io.Writer) error { return writeHeatmapCSV(w, bins) }); err != nil {
+				return nil, err
+			}
+			fmt.Println("Generated: ", opts.heatmapCSV)
+		}
+		lyr = heatmapLayer(bins, opts.heatmapCell)
+	}
+
+	if opts.mode == "choropleth" {
+		counts := countRegions(opts.districts, markerPositions(lyr))
+		if err := writeRegionCounts(os.Stdout, counts); err != nil {
+			return nil, err
+		}
+		lyr = choroplethLayer(opts.districts, counts, opts.theme)
+	}
+
+	lyr.style = elementStyle{Stroke: opts.stroke, Circles: opts.markerStyle == MarkerCircle}
+	if opts.mode == "zooms" {
+		return renderZooms(lyr, sum, baseName, opts)
+	}
+
+	return renderImage(lyr, sum, baseName, hashedPath, opts)
+}
+
+// renderImage renders the layer with its overlays and writes the image and
+// its sidecar, to hashedPath with -name-by-hash.
+func renderImage(lyr *layer, sum *summary, baseName, hashedPath string, opts options) (*fileResult, error) {
+	img, vp, err := render(lyr, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// the framing the render used, also saved in the sidecar, to reproduce
+	// it with -center/-fixed-zoom or match it in a web map
+	fmt.Println(fmt.Sprintf("View: center %f,%f, zoom %d, size %dx%d",
+		vp.Center.Lat.Degrees(), vp.Center.Lng.Degrees(), vp.Zoom, vp.Width, vp.Height))
+	reportDistortion(vp)
+	if pixelScale != 1 {
+		fmt.Println(fmt.Sprintf("Scale: @%gx, logical size %dx%d", pixelScale,
+			int(float64(vp.Width)/pixelScale), int(float64(vp.Height)/pixelScale)))
+	}
+
+	if len(lyr.glyphs) > 0 {
+		img = drawGlyphs(img, lyr, vp)
+	}
+
+	if opts.markerOutline != "" {
+		c, err := parseColor(opts.markerOutline)
+		if err != nil {
+			return nil, err
+		}
+		img = outlineMarkers(img, lyr, vp, c, opts.markerOutlineWidth*pixelScale)
+	}
+
+	if sum.Ellipse != nil {
+		img = drawEllipse(img, sum.Ellipse, vp, opts.theme)
+	}
+
+	corners := newCornerStack(opts.baseImage == "" && !opts.noBasemap)
+
+	distanceLegend := opts.mode == "line" && opts.colorByDistance
+	if opts.legendOut != "" {
+		legend := legendImage(lyr.legend, distanceLegend, opts.distanceMin, opts.distanceMax, opts.theme)
+		if legend == nil {
+			fmt.Println("Warning: -legend-out has nothing to show without -group-col or -color-by-distance")
+		} else if err := gg.SavePNG(opts.legendOut, legend); err != nil {
+			return nil, err
+		} else {
+			fmt.Println("\nGenerated: ", opts.legendOut)
+		}
+	} else {
+		if distanceLegend {
+			img = drawDistanceLegend(img, opts.distanceMin, opts.distanceMax, corners, opts.theme)
+		}
+		if len(lyr.lineLegend) > 0 {
+			img = drawEntryLegend(img, lyr.lineLegend, corners, opts.theme)
+		}
+		if len(lyr.flowLegend) > 0 {
+			img = drawFlowLegend(img, lyr.flowLegend, corners, opts.theme)
+		}
+	}
+
+	if opts.statsBox != "" {
+		img = drawStatsBox(img, sum, opts.statsBox, corners, opts.theme)
+	}
+
+	if opts.northArrow != "" {
+		img = drawNorthArrow(img, opts.northArrow, corners, opts.theme)
+	}
+
+	if opts.inset != "" {
+		inset, err := renderInset(vp, int(float64(opts.insetSize)*pixelScale), opts)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("Warning: -inset not drawn: %v", err))
+		} else {
+			img = drawInset(img, inset, opts.inset, corners, opts.theme)
+		}
+	}
+
+	if opts.drawIDs {
+		img = drawMarkerIDs(img, lyr, vp, opts.theme)
+	}
+
+	if len(lyr.hops) > 0 {
+		img = drawHopLabels(img, lyr.hops, vp, opts.theme)
+	}
+
+	if opts.centroidLabel && sum.Centroid != nil {
+		img = drawCentroidLabel(img, *sum.Centroid, vp, opts.theme)
+	}
+
+	img = flipImage(img, opts.flipX, opts.flipY)
+
+	// every input's render held until the sheet is tiled adds up
+	res := &fileResult{Summary: sum}
+	if opts.contactSheet != "" {
+		res.Image = img
+	}
+	if opts.sheetOnly {
+		return res, nil
+	}
+
+	outFilePath := opts.output
+	if outFilePath == "" {
+		outFilePath = hashedPath
+	}
+	if outFilePath == "" && opts.nameTemplate != nil {
+		b := img.Bounds()
+		name, err := executeName(opts.nameTemplate, nameFields{
+			Base:      baseName,
+			Mode:      opts.mode,
+			RowCount:  sum.RowCount,
+			Timestamp: time.Now().Unix(),
+			Width:     b.Dx(),
+			Height:    b.Dy(),
+		}, "."+opts.imageFormat)
+		if err != nil {
+			return nil, err
+		}
+
+		outFilePath, err = outputPath(name, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if outFilePath == "" {
+		outFilePath, err = outputPath(fmt.Sprintf("img-%s-%s-%d-%d%s.%s", baseName, opts.mode, sum.RowCount, time.Now().Unix(), scaleSuffix(), opts.imageFormat), opts)
+		if err != nil {
+			return nil, err
+		}
+	} else if err := os.MkdirAll(filepath.Dir(outFilePath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := saveImage(outFilePath, img, opts); err != nil {
+		return nil, err
+	}
+
+	md := metadata{
+		Input:  opts.filename,
+		Mode:   opts.mode,
+		Rows:   sum.RowCount,
+		Lat:    vp.Center.Lat.Degrees(),
+		Lng:    vp.Center.Lng.Degrees(),
+		Zoom:   vp.Zoom,
+		Width:  vp.Width,
+		Height: vp.Height,
+	}
+	if err := writeMetadata(outFilePath, md); err != nil {
+		return nil, err
+	}
+	if opts.vectorExport {
+		if err := writeVector(outFilePath, lyr); err != nil {
+			return nil, err
+		}
+	}
 
-	flag.StringVar(&mode, "mode", "plot", "a string var")
-	flag.StringVar(&filename, "file", "", "a string var")
-	flag.IntVar(&limit, "limit", 0, "an int var")
+	fmt.Println("\nGenerated: ", outFilePath)
 
-	flag.Parse()
+	res.Path = outFilePath
+	res.Metadata = &md
+	return res, nil
+}
 
-	fmt.Println(fmt.Sprintf("Input: %s, mode: %s, limit: %d", filename, mode, limit))
-	ctx, rowCount, err := markLocations(limit, filename, mode)
-	if err != nil {
-		terminate(err)
-	}
+// render draws the layer either over a fresh basemap or, with -base-image,
+// over a previous render using the framing stored in its sidecar.
+func render(lyr *layer, opts options) (image.Image, Viewport, error) {
+	if opts.baseImage != "" {
+		md, err := readMetadata(opts.baseImage)
+		if err != nil {
+			return nil, Viewport{}, err
+		}
 
-	img, err := ctx.Render()
-	if err != nil {
-		terminate(err)
+		base, err := gg.LoadPNG(opts.baseImage)
+		if err != nil {
+			return nil, Viewport{}, err
+		}
+
+		vp := md.viewport()
+		scaleLayer(lyr)
+		if b := base.Bounds(); b.Dx() != vp.Width || b.Dy() != vp.Height {
+			return nil, vp, ErrBadInput
+		}
+
+		if opts.jitter > 0 {
+			jitterMarkers(lyr.markers, vp, opts.jitter, opts.seed)
+		}
+		clipToView(lyr, vp, opts)
+
+		return drawLayer(base, lyr, vp), vp, nil
 	}
 
-	baseName := filepath.Base(filename)
-	baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
+	vp, _ := frame(lyr, opts)
+	scaleLayer(lyr)
 
-	if _, err := os.Stat(ImagesDir); os.IsNotExist(err) {
-		os.Mkdir(ImagesDir, os.ModePerm)
+	if opts.jitter > 0 {
+		jitterMarkers(lyr.markers, vp, opts.jitter, opts.seed)
 	}
+	clipToView(lyr, vp, opts)
 
-	outFilePath := path.Join(ImagesDir, fmt.Sprintf("img-%s-%s-%d-%d.png", baseName, mode, rowCount, time.Now().Unix()))
-	if err := gg.SavePNG(outFilePath, img); err != nil {
-		terminate(err)
+	if opts.noBasemap {
+		return drawLayer(backgroundCanvas(vp, opts.theme), lyr, vp), vp, nil
 	}
 
-	fmt.Println("\nGenerated: ", outFilePath)
-}
+	if opts.grayscale {
+		// the data is drawn over the desaturated tiles, keeping its colors
+		base, err := renderTiles(&layer{basemapOnly: true}, vp, opts)
+		if errors.Is(err, ErrDeadline) {
+			fmt.Println(fmt.Sprintf("Warning: %v, drawing without the basemap", err))
+			base, err = backgroundCanvas(vp, opts.theme), nil
+		}
+		if err != nil {
+			return nil, vp, err
+		}
+		return drawLayer(desaturate(base), lyr, vp), vp, nil
+	}
 
-func markLocations(limit int, filename, mode string) (*sm.Context, int, error) {
-	ctx := sm.NewContext()
-	ctx.SetSize(600, 400)
+	img, err := renderTiles(lyr, vp, opts)
+	if errors.Is(err, ErrDeadline) {
+		// the partial output: the data without the basemap
+		fmt.Println(fmt.Sprintf("Warning: %v, drawing without the basemap", err))
+		return drawLayer(backgroundCanvas(vp, opts.theme), lyr, vp), vp, nil
+	}
+	if err == nil && lyr.drawnByGG() {
+		img = drawLayer(img, lyr.overlay(), vp)
+	}
+	return img, vp, err
+}
 
-	filePath, err := filepath.Abs(filename)
-	if err != nil {
-		return ctx, 0, err
+// backgroundCanvas is a blank image for renders without tiles, filled with
+// the theme background when it has one.
+func backgroundCanvas(vp Viewport, t theme) *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, vp.Width, vp.Height))
+	if t.Background.A > 0 {
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(t.Background), image.Point{}, draw.Src)
 	}
 
-	if stat, e := os.Stat(filePath); e == nil && stat.IsDir() {
-		return ctx, 0, ErrBadInput
+	return canvas
+}
+
+func markLocations(opts options) (*layer, *summary, error) {
+	if !opts.noOrderDetect {
+		opts.order = &orderDetector{}
+	}
+	p := newPlotter(opts)
+	if opts.spillAfter > 0 {
+		spill, err := newSpillFile(opts.spillAfter)
+		if err != nil {
+			return p.lyr, p.sum, err
+		}
+		p.lyr.spill = spill
 	}
 
-	file, err := os.Open(filePath)
+	file, err := openSource(opts.filename, opts)
 	if err != nil {
-		return ctx, 0, err
+		return p.lyr, p.sum, err
 	}
 
 	defer file.Close()
 
+	// -merge rows are traced back to their input through the merged
+	// stream offset, which a -byte-start shard no longer matches
+	merged, _ := file.(*mergedReader)
+	sharded := opts.byteStart > 0 || opts.byteEnd > 0
+
+	hashed := opts.hasher.reader(file)
+	input := hashed
+	if sharded {
+		input, err = newShardReader(hashed, opts.byteStart, opts.byteEnd, opts.headerRows)
+		if err != nil {
+			return p.lyr, p.sum, err
+		}
+	}
+
+	// routes held back for -sort-by-col
+	var sorted []route
+
+	var sample precheckSample
+
 	rowCount := -1
 	if file != nil {
-		reader := csv.NewReader(file)
+		reader := csv.NewReader(input)
+		reader.Comment = opts.comment
+		reader.Comma = opts.delimiter
+		reader.LazyQuotes = opts.lazyQuotes
+		reader.FieldsPerRecord = -1
 
 		for {
+			if isInterrupted() || deadlinePassed() {
+				break
+			}
+
 			record, err := reader.Read()
 			if err == io.EOF {
 				break
 			}
 
 			rowCount++
-			if rowCount == 0 {
+			p.raw = record
+			if err != nil {
+				// a malformed row is skipped, but csv.Reader returns the
+				// same read error forever, e.g. for a truncated .gz
+				var parseErr *csv.ParseError
+				if !errors.As(err, &parseErr) {
+					return p.lyr, p.sum, fmt.Errorf("%s: reading row %d: %w", opts.filename, rowCount, err)
+				}
+				p.skip(route{Row: rowCount}, err)
 				continue
-			} else if limit == 0 || rowCount < limit {
-				x1, y1, err := getLatLong(record[9])
-				if err != nil {
+			}
+
+			p.noteRowID(rowCount, record)
+			p.file = opts.filename
+			if merged != nil && !sharded {
+				p.file = merged.fileAt(reader.InputOffset())
+			}
+
+			if rowCount >= opts.headerRows && opts.cellTransforms != nil {
+				if opts.errorRows != nil {
+					p.raw = append([]string(nil), record...)
+				}
+				if err := opts.cellTransforms.apply(record); err != nil {
+					p.skip(route{Row: rowCount}, err)
 					continue
 				}
+			}
+
+			if rowCount < opts.headerRows {
+				// the last header row names the columns
+				p.header = record
+				if opts.errorRows != nil {
+					opts.errorRows.noteHeader(opts.filename, record)
+				}
+				checkHeaderRow(record, rowCount, opts)
+				continue
+			} else if rowCount == opts.headerRows {
+				checkFirstDataRow(record, rowCount, opts)
+			}
+			if !opts.noPrecheck {
+				if err := sample.check(record, p.header, opts); err != nil {
+					return p.lyr, p.sum, err
+				}
+			}
+
+			if opts.limit != 0 && rowCount >= opts.limit {
+				p.pass(rowCount, "beyond -limit")
+				continue
+			}
+
+			if !p.regionAllowed(record) {
+				p.pass(rowCount, "region excluded by -allow-regions/-deny-regions")
+				continue
+			}
+
+			if !p.rangeAllowed(record, rowCount) {
+				continue
+			}
+
+			if opts.waypointsCol >= 0 {
+				if !p.addWaypoints(record, rowCount) {
+					break
+				}
+				continue
+			}
+
+			if p.groupFull(record) {
+				p.pass(rowCount, "group reached -limit-per-group")
+				continue
+			}
 
-				x2, y2, err := getLatLong(record[12])
+			var rt route
+			if opts.roleCol >= 0 {
+				var ready bool
+				rt, ready, err = p.pivot(record, rowCount)
+				if err != nil {
+					p.skip(rt, err)
+					continue
+				}
+				if !ready {
+					continue
+				}
+			} else {
+				rt, err = parseRoute(record, rowCount, opts)
 				if err != nil {
+					p.skip(rt, err)
 					continue
 				}
+			}
+			rt.File = p.file
+
+			ends := []s2.LatLng{rt.Src, rt.Dst}
+			if opts.origin != nil {
+				ends = ends[1:]
+			}
+			if outsideMask(opts.mask, ends...) {
+				p.sum.Outside++
+				p.pass(rowCount, "outside -mask")
+				continue
+			}
+
+			if !opts.countOnly && (opts.sortCol >= 0 || opts.mode == "trail" || opts.mode == "flows") {
+				sorted = append(sorted, rt)
+				continue
+			}
+
+			if !p.add(rt) {
+				break
+			}
+		}
+	}
 
-				ctx.AddMarker(sm.NewMarker(s2.LatLngFromDegrees(x1, y1), color.RGBA{0x00, 0xff, 0x00, 0xff}, 4.0)) //source
-				ctx.AddMarker(sm.NewMarker(s2.LatLngFromDegrees(x2, y2), color.RGBA{0xff, 0, 0, 0xff}, 4.0))       //destination
+	if !opts.noPrecheck && !isInterrupted() && !deadlinePassed() {
+		if err := sample.end(); err != nil {
+			return p.lyr, p.sum, err
+		}
+	}
 
-				if mode == "line" {
-					var pos []s2.LatLng
-					pos = append(pos, s2.LatLngFromDegrees(x1, y1))
-					pos = append(pos, s2.LatLngFromDegrees(x2, y2))
+	p.unpaired()
 
-					ctx.AddPath(sm.NewPath(pos, color.RGBA{0x00, 0x00, 0x00, 0xff}, 1.0))
-				}
+	if opts.mode == "trail" {
+		p.addTrails(sorted)
+	} else if opts.mode == "flows" {
+		p.addFlows(sorted)
+	} else if opts.sortCol >= 0 {
+		sortRoutes(sorted, opts.sortCol, opts.sortDesc)
+		for _, rt := range sorted {
+			if p.groupFull(rt.Record) {
+				p.pass(rt.Row, "group reached -limit-per-group")
+				continue
+			}
+
+			if !p.add(rt) {
+				break
 			}
 		}
 	}
 
-	return ctx, rowCount, nil
+	p.finish()
+
+	if !isInterrupted() && !deadlinePassed() {
+		if err := opts.hasher.finish(hashed); err != nil {
+			return p.lyr, p.sum, fmt.Errorf("%s: %w", opts.filename, err)
+		}
+	}
+
+	p.sum.RowCount = rowCount
+	return p.lyr, p.sum, nil
+}
+
+// markerCapReached reports, with a warning, whether adding n more markers
+// would exceed -max-markers.
+func markerCapReached(lyr *layer, n, row int, opts options) bool {
+	if opts.maxMarkers <= 0 || lyr.markerCount()+n <= opts.maxMarkers {
+		return false
+	}
+
+	fmt.Println(fmt.Sprintf("Warning: reached -max-markers %d at row %d, the map is partial; use -limit to plot fewer rows or -max-markers 0 to disable the cap",
+		opts.maxMarkers, row))
+	return true
+}
+
+// scaleMarkerSizes sets each marker's size between min and max according to
+// its normalized value. Markers without a value keep their base size.
+func scaleMarkerSizes(markers []*sm.Marker, values map[*sm.Marker]float64, min, max float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+
+	for _, m := range markers {
+		v, ok := values[m]
+		if !ok {
+			continue
+		}
+
+		t := 1.0
+		if hi > lo {
+			t = (v - lo) / (hi - lo)
+		}
+		m.Size = min + t*(max-min)
+	}
+}
+
+// writeFile creates path and writes it with write.
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// parseIntList parses a comma separated list of integers.
+func parseIntList(value string) ([]int, error) {
+	var list []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a list of integers", ErrBadInput, value)
+		}
+		list = append(list, n)
+	}
+
+	return list, nil
+}
+
+// parseDash parses a -dash pattern of positive, comma separated lengths.
+func parseDash(value string) ([]float64, error) {
+	var pattern []float64
+	for _, part := range strings.Split(value, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || f <= 0 {
+			return nil, fmt.Errorf("%w: dash %q, expected positive comma separated lengths", ErrBadInput, value)
+		}
+		pattern = append(pattern, f)
+	}
+
+	return pattern, nil
+}
+
+// precheckRows is how many data rows the precheck samples. A single
+// placeholder cell, like the "," seller of files/sample.csv, doesn't fail a
+// file whose next rows are fine; a wrong column fails them all.
+const precheckRows = 5
+
+// precheckSample runs precheck over the first precheckRows data rows and
+// fails only when none of them pass.
+type precheckSample struct {
+	rows   int
+	passed bool
+	err    error  // the first row's failure
+	cols   string // the first row's columns, listed
+}
+
+// check samples one data row. It returns an error once the whole sample has
+// failed.
+func (s *precheckSample) check(record, header []string, opts options) error {
+	if s.passed || s.rows >= precheckRows {
+		return nil
+	}
+
+	s.rows++
+	err := precheck(record, opts)
+	if err == nil {
+		s.passed = true
+		return nil
+	}
+	if s.err == nil {
+		s.err, s.cols = err, listColumns(header, record, opts)
+	}
+	if s.rows == precheckRows {
+		return s.end()
+	}
+
+	return nil
+}
+
+// end reports a failed sample, for inputs shorter than precheckRows too.
+func (s *precheckSample) end() error {
+	if s.passed || s.err == nil {
+		return nil
+	}
+	s.passed = true // report once
+
+	more := ""
+	if s.rows > 1 {
+		more = fmt.Sprintf(", nor are the next %d", s.rows-1)
+	}
+
+	return fmt.Errorf("%w%s (use -no-precheck to skip this check)\n%s", s.err, more, s.cols)
+}
+
+// precheck verifies that the selected columns of a data row look like
+// coordinates, so a wrong -src-col/-dst-col fails fast instead of plotting
+// nothing.
+func precheck(record []string, opts options) error {
+	if opts.waypointsCol >= 0 {
+		if opts.waypointsCol >= len(record) {
+			return fmt.Errorf("%w: column %d not present in first data row (%d columns)", ErrBadInput, opts.waypointsCol, len(record))
+		}
+
+		if stops, _ := parseWaypoints(record[opts.waypointsCol], opts); len(stops) == 0 {
+			return fmt.Errorf("%w: column %d of first data row is %q, not a list of lat,lng pairs", ErrBadInput, opts.waypointsCol, record[opts.waypointsCol])
+		}
+
+		return nil
+	}
+
+	cols := []int{opts.srcCol, opts.dstCol}
+	if opts.origin != nil {
+		cols = cols[1:]
+	} else if opts.roleCol >= 0 {
+		cols = cols[:1]
+	}
+
+	for _, col := range cols {
+		if col < 0 || col >= len(record) {
+			return fmt.Errorf("%w: column %d not present in first data row (%d columns)", ErrBadInput, col, len(record))
+		}
+
+		if _, _, err := parseLocation(record[col], opts); err != nil {
+			return fmt.Errorf("%w: column %d of first data row is %q, not a lat,lng pair", ErrBadInput, col, record[col])
+		}
+	}
+
+	return nil
+}
+
+// locate parses the coordinates in column col, falling back to geocoding
+// column geocodeCol when the coordinates are empty and a geocoder is set.
+func locate(record []string, col, geocodeCol int, opts options) (float64, float64, error) {
+	cell := record[col]
+	if opts.geocoder == nil || geocodeCol < 0 || geocodeCol >= len(record) || !isMissingCoordinate(cell) {
+		return parseLocation(cell, opts)
+	}
+
+	query := strings.TrimSpace(record[geocodeCol])
+	if query == "" {
+		return 0, 0, ErrLatLong
+	}
+
+	x, y, err := opts.geocoder.Geocode(query)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return x, y, checkBounds(x, y)
+}
+
+// parseLocation parses a coordinate cell according to -coord-type.
+func parseLocation(cell string, opts options) (float64, float64, error) {
+	if opts.coordType == CoordGeohash {
+		x, y, err := decodeGeohash(cell)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		return x, y, checkBounds(x, y)
+	}
+
+	if opts.transform != nil {
+		a, b, err := parsePair(cell)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		x, y, err := opts.transform.Transform(a, b)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		return x, y, checkBounds(x, y)
+	}
+
+	x, y, err := parsePair(cell)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if opts.coordUnit == UnitRadians {
+		x, y = x*180/math.Pi, y*180/math.Pi
+	}
+
+	x, y = opts.order.apply(x, y)
+	return x, y, checkBounds(x, y)
 }
 
 func getLatLong(latlong string) (float64, float64, error) {
+	x, y, err := parsePair(latlong)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := checkBounds(x, y); err != nil {
+		return 0, 0, err
+	}
+
+	return x, y, err
+}
+
+// parsePair splits a "x,y" cell into its two numbers without validating
+// them as a location.
+func parsePair(latlong string) (float64, float64, error) {
 	var err error
 
-	if latlong == "" || latlong == "," || latlong == "-999,-999" {
+	if strings.Trim(latlong, ",; ") == "" {
 		return 0, 0, ErrLatLong
 	}
 
-	xy := strings.Split(latlong, ",")
-	if len(xy) != 2 {
-		return 0, 0, ErrLatLong
+	xy, err := splitPair(latlong)
+	if err != nil {
+		return 0, 0, err
 	}
 
-	x, err := strconv.ParseFloat(strings.TrimSpace(xy[0]), 64)
+	x, err := parseCoordinate(xy[0])
 	if err != nil {
 		return 0, 0, err
 	}
 
-	y, err := strconv.ParseFloat(strings.TrimSpace(xy[1]), 64)
+	y, err := parseCoordinate(xy[1])
 	if err != nil {
 		return 0, 0, err
 	}
 
-	if y < SouthernmostPoint || y > NorthernmostPoint || x > WesternmostPoint || x < EasternmostPoint {
-		return 0, 0, ErrLatLongOutOfRange
+	if isSentinel(x, y) {
+		return 0, 0, ErrLatLong
 	}
 
-	return x, y, err
+	return x, y, nil
 }
 
-func terminate(err error) {
+// sentinels are the placeholder pairs treated as a missing location. They
+// are checked independently of activeBounds and replaced by -sentinels.
+var sentinels = [][2]float64{{-999, -999}}
+
+// parseSentinels parses a ";" separated list of "x,y" pairs. An empty
+// value disables sentinel matching.
+func parseSentinels(value string) ([][2]float64, error) {
+	var pairs [][2]float64
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		xy := strings.Split(item, ",")
+		if len(xy) != 2 {
+			return nil, fmt.Errorf("%w: sentinel %q, expected x,y", ErrBadInput, item)
+		}
+
+		var pair [2]float64
+		for i, v := range xy {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: sentinel %q: %v", ErrBadInput, item, err)
+			}
+			pair[i] = f
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
+func isSentinel(x, y float64) bool {
+	for _, s := range sentinels {
+		if x == s[0] && y == s[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// boundingBox is the accepted coordinate range, in degrees.
+type boundingBox struct {
+	MinLat, MinLng, MaxLat, MaxLng float64
+}
+
+// activeBounds defaults to the boundary points and is replaced by -bbox.
+var activeBounds = boundingBox{
+	MinLat: EasternmostPoint,
+	MinLng: SouthernmostPoint,
+	MaxLat: WesternmostPoint,
+	MaxLng: NorthernmostPoint,
+}
+
+// parseBoundingBox parses "minLat,minLng,maxLat,maxLng".
+func parseBoundingBox(value string) (boundingBox, error) {
+	var b boundingBox
+
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return b, fmt.Errorf("%w: bbox %q, expected minLat,minLng,maxLat,maxLng", ErrBadInput, value)
+	}
+
+	dst := []*float64{&b.MinLat, &b.MinLng, &b.MaxLat, &b.MaxLng}
+	for i, part := range parts {
+		v, err := parseCoordinate(part)
+		if err != nil {
+			return b, fmt.Errorf("%w: bbox %q: %v", ErrBadInput, value, err)
+		}
+		*dst[i] = v
+	}
+
+	if b.MinLat > b.MaxLat || b.MinLng > b.MaxLng {
+		return b, fmt.Errorf("%w: bbox %q, min is above max", ErrBadInput, value)
+	}
+
+	return b, nil
+}
+
+// checkBounds reports whether the lat (x), lng (y) pair is inside the
+// active bounding box.
+func checkBounds(x, y float64) error {
+	b := activeBounds
+	if y < b.MinLng || y > b.MaxLng || x > b.MaxLat || x < b.MinLat {
+		return ErrLatLongOutOfRange
+	}
+
+	return nil
+}
+
+// parseCoordinate parses a single coordinate component. Plain decimals are
+// parsed directly; otherwise degree symbols are stripped and a trailing
+// N/S/E/W hemisphere suffix sets the sign, e.g. "28.61° N" or "77.20°e".
+func parseCoordinate(value string) (float64, error) {
+	value = normalizeDecimal(strings.TrimSpace(value))
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		return v, nil
+	}
+
+	value = strings.NewReplacer("°", "", "º", "").Replace(value)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, ErrLatLong
+	}
+
+	sign := 1.0
+	switch value[len(value)-1] {
+	case 'N', 'n', 'E', 'e':
+		value = value[:len(value)-1]
+	case 'S', 's', 'W', 'w':
+		sign = -1.0
+		value = value[:len(value)-1]
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return sign * v, nil
+}
+
+// usage is the -h output: the synopsis, then every flag.
+func usage() {
 	// ~ won't expand if we use `file=~/some-file`, use ``-file ~/some-file` instead
-	fmt.Println("\nUsage: go run app.go -file <filename> -mode [plot|line] -limit [0|N]")
+	fmt.Fprintln(flag.CommandLine.Output(), "Usage: go run main.go -file <filename> [more files] -mode [plot|line|heatmap|extent|html] -limit [0|N] [options]")
+	flag.PrintDefaults()
+}
+
+func terminate(err error) {
+	removeSpills()
 	if err != nil {
+		finishAudit(AuditFailed, err)
 		fmt.Println("Error: ", err.Error())
+		fmt.Println("Run with -h for usage")
+		os.Exit(1)
 	}
 
+	finishAudit(AuditOK, nil)
 	os.Exit(0)
 }
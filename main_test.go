@@ -0,0 +1,293 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// testOptions returns the options main sets up with every flag at its
+// default, for tests to adjust.
+func testOptions(t *testing.T) options {
+	t.Helper()
+
+	opts := options{
+		mode:          "plot",
+		srcCol:        9,
+		dstCol:        12,
+		groupCol:      -1,
+		roleCol:       -1,
+		sizeCol:       -1,
+		srcGeocodeCol: -1,
+		dstGeocodeCol: -1,
+		waypointsCol:  -1,
+		labelCol:      -1,
+		transportCol:  -1,
+		timeCol:       -1,
+		idCol:         -1,
+		regionCol:     -1,
+		sortCol:       -1,
+		glyphCol:      -1,
+		headerRows:    1,
+		maxMarkers:    100000,
+		markerSize:    4,
+		sizeMin:       2,
+		sizeMax:       16,
+		widthMin:      1,
+		widthMax:      6,
+		coordType:     CoordLatLng,
+		coordUnit:     UnitDegrees,
+		distanceModel: DistanceSpherical,
+		distanceMax:   1000,
+		delimiter:     ',',
+		comment:       '#',
+		outDir:        t.TempDir(),
+	}
+
+	var err error
+	if opts.theme, err = parseTheme("light"); err != nil {
+		t.Fatal(err)
+	}
+	if opts.precision, err = parsePrecision("5"); err != nil {
+		t.Fatal(err)
+	}
+
+	return opts
+}
+
+func TestParseCoordinate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{in: "-6.2", want: -6.2},
+		{in: " 106.8 ", want: 106.8},
+		{in: "6.2° S", want: -6.2},
+		{in: "6.2°s", want: -6.2},
+		{in: "106.8°E", want: 106.8},
+		{in: "106.8 W", want: -106.8},
+		{in: "1.5 N", want: 1.5},
+		{in: "106.8º", want: 106.8},
+		{in: "°", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "north", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCoordinate(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCoordinate(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseCoordinate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGetLatLong(t *testing.T) {
+	tests := []struct {
+		in           string
+		wantX, wantY float64
+		wantErr      error
+	}{
+		{in: "-6.2,106.8", wantX: -6.2, wantY: 106.8},
+		{in: "6.2°S, 106.8°E", wantX: -6.2, wantY: 106.8},
+		{in: "-6.2", wantErr: ErrLatLong},
+		{in: ",", wantErr: ErrLatLong},
+		{in: "-999,-999", wantErr: ErrLatLong},
+		{in: "51.5,-0.1", wantErr: ErrLatLongOutOfRange},
+	}
+
+	for _, tt := range tests {
+		x, y, err := getLatLong(tt.in)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("getLatLong(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || x != tt.wantX || y != tt.wantY {
+			t.Errorf("getLatLong(%q) = %v, %v, %v, want %v, %v", tt.in, x, y, err, tt.wantX, tt.wantY)
+		}
+	}
+}
+
+func TestParseBoundingBox(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    boundingBox
+		wantErr bool
+	}{
+		{in: "-11,95,6,141", want: boundingBox{-11, 95, 6, 141}},
+		{in: "40S, 170E, 30S, 170W", wantErr: true},
+		{in: "-47, 166, -34, 179", want: boundingBox{-47, 166, -34, 179}},
+		{in: "1,2,3", wantErr: true},
+		{in: "a,2,3,4", wantErr: true},
+		{in: "5,2,3,4", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseBoundingBox(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrBadInput) {
+				t.Errorf("parseBoundingBox(%q) error = %v, want ErrBadInput", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseBoundingBox(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheckBoundsActiveBounds(t *testing.T) {
+	defer func(b boundingBox) { activeBounds = b }(activeBounds)
+
+	if err := checkBounds(-41.3, 174.8); !errors.Is(err, ErrLatLongOutOfRange) {
+		t.Errorf("Wellington inside the default bounds: %v", err)
+	}
+
+	activeBounds = boundingBox{-47, 166, -34, 179}
+	if err := checkBounds(-41.3, 174.8); err != nil {
+		t.Errorf("Wellington outside -bbox: %v", err)
+	}
+	if err := checkBounds(-6.2, 106.8); !errors.Is(err, ErrLatLongOutOfRange) {
+		t.Errorf("Jakarta inside -bbox: %v", err)
+	}
+}
+
+func TestParseSentinels(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    [][2]float64
+		wantErr bool
+	}{
+		{in: "-999,-999", want: [][2]float64{{-999, -999}}},
+		{in: "-999,-999; 0,0 ;", want: [][2]float64{{-999, -999}, {0, 0}}},
+		{in: ""},
+		{in: "0", wantErr: true},
+		{in: "0,zero", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSentinels(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrBadInput) {
+				t.Errorf("parseSentinels(%q) error = %v, want ErrBadInput", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("parseSentinels(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestSentinelsWithoutBounds(t *testing.T) {
+	defer func(b boundingBox, s [][2]float64) { activeBounds, sentinels = b, s }(activeBounds, sentinels)
+	activeBounds = boundingBox{MinLat: -90, MinLng: -180, MaxLat: 90, MaxLng: 180}
+
+	tests := []struct {
+		name      string
+		sentinels [][2]float64
+		in        string
+		wantErr   bool
+	}{
+		{name: "0,0 kept by default", sentinels: [][2]float64{{-999, -999}}, in: "0,0"},
+		{name: "0,0 dropped as sentinel", sentinels: [][2]float64{{-999, -999}, {0, 0}}, in: "0,0", wantErr: true},
+		{name: "-999,-999 dropped before the range check", sentinels: [][2]float64{{-999, -999}}, in: "-999,-999", wantErr: true},
+		{name: "sentinels disabled", in: "51.5,-0.1"},
+	}
+
+	for _, tt := range tests {
+		sentinels = tt.sentinels
+		_, _, err := getLatLong(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrLatLong) {
+				t.Errorf("%s: error = %v, want ErrLatLong", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+func TestParseLocationRadians(t *testing.T) {
+	tests := []struct {
+		name     string
+		cell     string
+		unit     string
+		detect   bool
+		lat, lng float64
+		wantErr  error
+	}{
+		{name: "radians", cell: "-0.1084,1.8648", unit: UnitRadians, lat: -6.2109, lng: 106.845},
+		{name: "radians lng,lat detected", cell: "1.8648,-0.1084", unit: UnitRadians, detect: true, lat: -6.2109, lng: 106.845},
+		{name: "radians outside bounds", cell: "0.9,-0.002", unit: UnitRadians, wantErr: ErrLatLongOutOfRange},
+		{name: "degrees read as is", cell: "-0.1084,1.8648", unit: UnitDegrees, wantErr: ErrLatLongOutOfRange},
+	}
+
+	for _, tt := range tests {
+		opts := testOptions(t)
+		opts.coordUnit = tt.unit
+		if tt.detect {
+			opts.order = &orderDetector{}
+		}
+
+		lat, lng, err := parseLocation(tt.cell, opts)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || math.Abs(lat-tt.lat) > 1e-3 || math.Abs(lng-tt.lng) > 1e-3 {
+			t.Errorf("%s: parseLocation = %v, %v, %v, want %v, %v", tt.name, lat, lng, err, tt.lat, tt.lng)
+		}
+	}
+}
+
+func TestPrecheckSample(t *testing.T) {
+	row := func(src string) []string {
+		r := make([]string, 13)
+		r[9], r[12] = src, "-6.1,106.7"
+		return r
+	}
+	bad, good := row(","), row("-6.2,106.8")
+
+	tests := []struct {
+		name string
+		rows [][]string
+		fail bool
+	}{
+		{name: "first row good", rows: [][]string{good, bad}},
+		{name: "placeholder first row", rows: [][]string{bad, bad, good}},
+		{name: "whole sample bad", rows: [][]string{bad, bad, bad, bad, bad, good}, fail: true},
+		{name: "short input bad", rows: [][]string{bad, bad}, fail: true},
+		{name: "no data rows"},
+	}
+
+	for _, tt := range tests {
+		var s precheckSample
+		var err error
+		for _, r := range tt.rows {
+			if err = s.check(r, nil, testOptions(t)); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = s.end()
+		}
+		if (err != nil) != tt.fail {
+			t.Errorf("%s: err = %v, want failure %v", tt.name, err, tt.fail)
+		}
+		if err != nil && !errors.Is(err, ErrBadInput) {
+			t.Errorf("%s: err = %v, want ErrBadInput", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// manifestEntry is one output of the run in the -manifest index.
+type manifestEntry struct {
+	Index  int    `json:"index"`
+	Output string `json:"output"`
+	Key    string `json:"key"` // input base name, e.g. the group or date a split file is named by
+
+	metadata
+
+	Routes  int  `json:"routes"`
+	Skipped bool `json:"skipped,omitempty"` // up to date, not rendered again
+}
+
+// writeManifest indexes every written output, numbered in input order, so
+// downstream tools such as ffmpeg or a report builder can take the batch
+// in a fixed order. Runs that wrote nothing are left out.
+func writeManifest(w io.Writer, files []string, results []*fileResult) error {
+	entries := []manifestEntry{}
+	for i, res := range results {
+		if res.Path == "" {
+			continue
+		}
+
+		e := manifestEntry{Index: len(entries) + 1, Output: res.Path, Key: sourceBaseName(files[i]), Skipped: res.Skipped}
+		if res.Metadata != nil {
+			e.metadata = *res.Metadata
+		} else if md, err := readMetadata(res.Path); err == nil {
+			e.metadata = md
+		} else {
+			e.Input = files[i]
+		}
+		if res.Summary != nil {
+			e.Routes = res.Summary.Routes
+		}
+		entries = append(entries, e)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
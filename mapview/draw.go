@@ -0,0 +1,103 @@
+package mapview
+
+import (
+	"math"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/fogleman/gg"
+)
+
+// DrawOnto draws areas, paths and markers onto a caller supplied context,
+// e.g. one panel of a multi-panel figure. vp maps coordinates to pixels of
+// dc; translate dc beforehand to place the map elsewhere within it.
+func DrawOnto(dc *gg.Context, vp Viewport, areas []*sm.Area, paths []*sm.Path, markers []*sm.Marker) {
+	for _, a := range areas {
+		DrawArea(dc, a, vp)
+	}
+	dc.SetLineCap(gg.LineCapRound)
+	dc.SetLineJoin(gg.LineJoinRound)
+	for _, p := range paths {
+		DrawPath(dc, p, vp)
+	}
+	for _, m := range markers {
+		DrawMarker(dc, m, vp, false)
+	}
+}
+
+// DrawPath strokes the path in its color and weight.
+func DrawPath(dc *gg.Context, p *sm.Path, vp Viewport) {
+	if len(p.Positions) < 2 {
+		return
+	}
+
+	dc.ClearPath()
+	for i, pos := range p.Positions {
+		x, y := vp.Project(pos)
+		if i == 0 {
+			dc.MoveTo(x, y)
+		} else {
+			dc.LineTo(x, y)
+		}
+	}
+	dc.SetColor(p.Color)
+	dc.SetLineWidth(p.Weight)
+	dc.Stroke()
+}
+
+// DrawArea fills the area and strokes its outline.
+func DrawArea(dc *gg.Context, a *sm.Area, vp Viewport) {
+	if len(a.Positions) < 3 {
+		return
+	}
+
+	dc.ClearPath()
+	for i, pos := range a.Positions {
+		x, y := vp.Project(pos)
+		if i == 0 {
+			dc.MoveTo(x, y)
+		} else {
+			dc.LineTo(x, y)
+		}
+	}
+	dc.ClosePath()
+	dc.SetColor(a.Fill)
+	dc.FillPreserve()
+	dc.SetColor(a.Color)
+	dc.SetLineWidth(a.Weight)
+	dc.Stroke()
+}
+
+// DrawMarker draws the go-staticmaps pin shape with its tip at the marker
+// position, or a circle centered on it.
+func DrawMarker(dc *gg.Context, m *sm.Marker, vp Viewport, circle bool) {
+	MarkerPath(dc, m, vp, circle)
+	dc.SetColor(m.Color)
+	dc.FillPreserve()
+	dc.SetRGB(0, 0, 0)
+	dc.Stroke()
+}
+
+// MarkerPath sets the current path to the marker's pin or circle outline.
+func MarkerPath(dc *gg.Context, m *sm.Marker, vp Viewport, circle bool) {
+	if !circle {
+		pinPath(dc, m, vp)
+		return
+	}
+
+	x, y := vp.Project(m.Position)
+	dc.ClearPath()
+	dc.SetLineWidth(1.0)
+	dc.DrawCircle(x, y, 0.5*m.Size)
+}
+
+// pinPath sets the current path to the marker's pin outline.
+func pinPath(dc *gg.Context, m *sm.Marker, vp Viewport) {
+	x, y := vp.Project(m.Position)
+
+	dc.ClearPath()
+	dc.SetLineJoin(gg.LineJoinRound)
+	dc.SetLineWidth(1.0)
+	dc.DrawArc(x, y-m.Size, 0.5*m.Size, (90.0+60.0)*math.Pi/180.0, (360.0+90.0-60.0)*math.Pi/180.0)
+	dc.LineTo(x, y)
+	dc.ClosePath()
+}
@@ -0,0 +1,45 @@
+package mapview
+
+import (
+	"image/color"
+	"testing"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/fogleman/gg"
+	"github.com/golang/geo/s2"
+)
+
+func TestDrawOnto(t *testing.T) {
+	vp := Viewport{Center: s2.LatLngFromDegrees(-6.2, 106.8), Zoom: 11, Width: 200, Height: 100}
+	red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+	blue := color.RGBA{0x00, 0x00, 0xff, 0xff}
+
+	// a two panel figure, the map drawn into the right one
+	dc := gg.NewContext(400, 100)
+	dc.SetRGB(1, 1, 1)
+	dc.Clear()
+	dc.Push()
+	dc.Translate(200, 0)
+	path := sm.NewPath([]s2.LatLng{vp.Unproject(20, 50), vp.Unproject(180, 50)}, blue, 3)
+	marker := sm.NewMarker(vp.Center, red, 16)
+	DrawOnto(dc, vp, nil, []*sm.Path{path}, []*sm.Marker{marker})
+	dc.Pop()
+
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{name: "left panel untouched", x: 100, y: 50, want: color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{name: "path", x: 220, y: 50, want: blue},
+		{name: "pin head above the position", x: 300, y: 34, want: red},
+		{name: "beside the pin", x: 320, y: 30, want: color.RGBA{0xff, 0xff, 0xff, 0xff}},
+	}
+
+	img := dc.Image()
+	for _, tt := range tests {
+		if got := color.RGBAModel.Convert(img.At(tt.x, tt.y)).(color.RGBA); got != tt.want {
+			t.Errorf("%s: pixel %d,%d = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package mapview is the web-mercator projection and the drawing of map
+// elements that courierInfo renders with, for programs composing its maps
+// into their own figures.
+package mapview
+
+import (
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// Tile geometry
+const (
+	TileSize = 256
+	MaxZoom  = 15
+)
+
+// Viewport is a web-mercator view of the map: the center and zoom the map
+// is rendered at and the pixel size of the image.
+type Viewport struct {
+	Center s2.LatLng
+	Zoom   int
+	Width  int
+	Height int
+}
+
+// Mercator returns the normalized web-mercator position of ll, with x and y
+// in [0, 1] and y growing southwards.
+func Mercator(ll s2.LatLng) (float64, float64) {
+	x := (ll.Lng.Degrees() + 180.0) / 360.0
+	lat := ll.Lat.Radians()
+	y := (1.0 - math.Log(math.Tan(lat)+1.0/math.Cos(lat))/math.Pi) / 2.0
+	return x, y
+}
+
+// Unmercator is the inverse of Mercator. x beyond [0, 1] wraps around the
+// world.
+func Unmercator(x, y float64) s2.LatLng {
+	x -= math.Floor(x)
+	lng := x*360.0 - 180.0
+	lat := math.Atan(math.Sinh(math.Pi*(1-2*y))) * 180.0 / math.Pi
+	return s2.LatLngFromDegrees(lat, lng)
+}
+
+// worldSize is the width of the whole world in pixels at the viewport zoom.
+func (v Viewport) worldSize() float64 {
+	return float64(TileSize) * math.Exp2(float64(v.Zoom))
+}
+
+// Project converts ll to pixel coordinates within the viewport image.
+func (v Viewport) Project(ll s2.LatLng) (float64, float64) {
+	cx, cy := Mercator(v.Center)
+	x, y := Mercator(ll)
+
+	dx := x - cx
+	if dx > 0.5 {
+		dx--
+	} else if dx < -0.5 {
+		dx++
+	}
+
+	world := v.worldSize()
+	return float64(v.Width)/2 + dx*world, float64(v.Height)/2 + (y-cy)*world
+}
+
+// Unproject converts pixel coordinates within the viewport image back to a
+// lat/lng, the inverse of Project.
+func (v Viewport) Unproject(px, py float64) s2.LatLng {
+	cx, cy := Mercator(v.Center)
+	world := v.worldSize()
+
+	x := cx + (px-float64(v.Width)/2)/world
+	y := cy + (py-float64(v.Height)/2)/world
+
+	return Unmercator(x, y)
+}
+
+// FitViewport chooses the center and the largest zoom at which bounds fit
+// into a width x height image with margin pixels on each side. It mirrors
+// the auto-fit of go-staticmaps so the explicit view matches its output.
+func FitViewport(bounds s2.Rect, width, height int, margin float64) Viewport {
+	v := Viewport{Center: bounds.Center(), Zoom: MaxZoom, Width: width, Height: height}
+	if bounds.IsEmpty() || bounds.IsPoint() {
+		return v
+	}
+
+	w := (float64(width) - 2.0*margin) / float64(TileSize)
+	h := (float64(height) - 2.0*margin) / float64(TileSize)
+
+	minX, minY := Mercator(bounds.Lo())
+	maxX, maxY := Mercator(bounds.Hi())
+
+	dx := maxX - minX
+	for dx < 0 {
+		dx++
+	}
+	for dx > 1 {
+		dx--
+	}
+	dy := math.Abs(maxY - minY)
+
+	for zoom := 1; zoom < 30; zoom++ {
+		tiles := math.Exp2(float64(zoom))
+		if dx*tiles > w || dy*tiles > h {
+			v.Zoom = zoom - 1
+			return v
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,68 @@
+package mapview
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestProjectUnproject(t *testing.T) {
+	vp := Viewport{Center: s2.LatLngFromDegrees(-6.2, 106.8), Zoom: 11, Width: 600, Height: 400}
+
+	tests := []struct {
+		name   string
+		ll     s2.LatLng
+		vp     Viewport
+		px, py float64
+	}{
+		{name: "center", ll: vp.Center, vp: vp, px: 300, py: 200},
+		{name: "world origin at zoom 0", ll: s2.LatLngFromDegrees(0, 0), vp: Viewport{Width: 256, Height: 256}, px: 128, py: 128},
+		{name: "east edge at zoom 0", ll: s2.LatLngFromDegrees(0, 90), vp: Viewport{Width: 256, Height: 256}, px: 192, py: 128},
+		{name: "wraps the short way", ll: s2.LatLngFromDegrees(0, -179), vp: Viewport{Center: s2.LatLngFromDegrees(0, 179), Width: 256, Height: 256}, px: 128 + 2*256.0/360, py: 128},
+	}
+
+	for _, tt := range tests {
+		px, py := tt.vp.Project(tt.ll)
+		if math.Abs(px-tt.px) > 1e-6 || math.Abs(py-tt.py) > 1e-6 {
+			t.Errorf("%s: Project = %v, %v, want %v, %v", tt.name, px, py, tt.px, tt.py)
+		}
+
+		back := tt.vp.Unproject(px, py)
+		if math.Abs(back.Lat.Degrees()-tt.ll.Lat.Degrees()) > 1e-9 || math.Abs(back.Lng.Degrees()-tt.ll.Lng.Degrees()) > 1e-9 {
+			t.Errorf("%s: Unproject = %v, want %v", tt.name, back, tt.ll)
+		}
+	}
+}
+
+func TestFitViewport(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds s2.Rect
+		zoom   int
+	}{
+		{name: "empty", bounds: s2.EmptyRect(), zoom: MaxZoom},
+		{name: "point", bounds: s2.RectFromLatLng(s2.LatLngFromDegrees(-6.2, 106.8)), zoom: MaxZoom},
+		{name: "Jakarta", bounds: s2.RectFromLatLng(s2.LatLngFromDegrees(-6.4, 106.7)).AddPoint(s2.LatLngFromDegrees(-6.0, 107.0)), zoom: 10},
+		{name: "Indonesia", bounds: s2.RectFromLatLng(s2.LatLngFromDegrees(-11, 95)).AddPoint(s2.LatLngFromDegrees(6, 141)), zoom: 4},
+	}
+
+	for _, tt := range tests {
+		vp := FitViewport(tt.bounds, 600, 400, 10)
+		if vp.Zoom != tt.zoom || vp.Width != 600 || vp.Height != 400 {
+			t.Errorf("%s: FitViewport = %+v, want zoom %d", tt.name, vp, tt.zoom)
+			continue
+		}
+		if tt.bounds.IsEmpty() || tt.bounds.IsPoint() {
+			continue
+		}
+
+		// the corners land inside the image, past the margin
+		for _, corner := range []s2.LatLng{tt.bounds.Lo(), tt.bounds.Hi()} {
+			x, y := vp.Project(corner)
+			if x < 10-1e-6 || x > 590+1e-6 || y < 10-1e-6 || y > 390+1e-6 {
+				t.Errorf("%s: corner %v at %v, %v, outside the margin", tt.name, corner, x, y)
+			}
+		}
+	}
+}
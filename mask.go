@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/geo/s2"
+)
+
+// ring is a closed polygon ring of lng,lat positions, as in GeoJSON.
+type ring [][2]float64
+
+// polygon is an outer ring followed by its holes.
+type polygon []ring
+
+// mask is the union of the polygons of a -mask GeoJSON file.
+type mask []polygon
+
+// geoJSONInput holds the parts of a GeoJSON object a mask can be read
+// from: a FeatureCollection, a Feature or a bare geometry.
+type geoJSONInput struct {
+	Type        string          `json:"type"`
+	Features    []geoJSONInput  `json:"features"`
+	Geometry    *geoJSONInput   `json:"geometry"`
+	Geometries  []geoJSONInput  `json:"geometries"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// loadMask reads the Polygon and MultiPolygon geometries of a GeoJSON
+// file.
+func loadMask(filename string) (mask, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj geoJSONInput
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("%w: mask %s: %v", ErrBadInput, filename, err)
+	}
+
+	var m mask
+	if err := m.add(obj); err != nil {
+		return nil, fmt.Errorf("%w: mask %s: %v", ErrBadInput, filename, err)
+	}
+	if len(m) == 0 {
+		return nil, fmt.Errorf("%w: mask %s has no Polygon or MultiPolygon", ErrBadInput, filename)
+	}
+
+	return m, nil
+}
+
+func (m *mask) add(obj geoJSONInput) error {
+	switch obj.Type {
+	case "FeatureCollection":
+		for _, f := range obj.Features {
+			if err := m.add(f); err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if obj.Geometry != nil {
+			return m.add(*obj.Geometry)
+		}
+	case "GeometryCollection":
+		for _, g := range obj.Geometries {
+			if err := m.add(g); err != nil {
+				return err
+			}
+		}
+	case "Polygon":
+		var p polygon
+		if err := json.Unmarshal(obj.Coordinates, &p); err != nil {
+			return err
+		}
+		*m = append(*m, p)
+	case "MultiPolygon":
+		var ps []polygon
+		if err := json.Unmarshal(obj.Coordinates, &ps); err != nil {
+			return err
+		}
+		*m = append(*m, ps...)
+	}
+
+	return nil
+}
+
+// contains reports whether ll is inside any polygon of the mask.
+func (m mask) contains(ll s2.LatLng) bool {
+	for _, p := range m {
+		if p.contains(ll.Lng.Degrees(), ll.Lat.Degrees()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p polygon) contains(x, y float64) bool {
+	if len(p) == 0 || !p[0].contains(x, y) {
+		return false
+	}
+
+	for _, hole := range p[1:] {
+		if hole.contains(x, y) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// contains ray-casts from x, y; edges are treated as planar in degrees,
+// which is precise enough for city-sized regions.
+func (r ring) contains(x, y float64) bool {
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		xi, yi := r[i][0], r[i][1]
+		xj, yj := r[j][0], r[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+// outsideMask reports whether any of the points falls outside the -mask.
+func outsideMask(m mask, points ...s2.LatLng) bool {
+	if m == nil {
+		return false
+	}
+
+	for _, ll := range points {
+		if !m.contains(ll) {
+			return true
+		}
+	}
+
+	return false
+}
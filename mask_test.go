@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+// jakartaMask is a square around Jakarta with a hole in its north,
+// next to a second square around Bandung.
+const jakartaMask = `{"type": "FeatureCollection", "features": [
+  {"type": "Feature", "properties": {}, "geometry": {"type": "Polygon", "coordinates": [
+    [[106.6, -6.4], [107.0, -6.4], [107.0, -6.0], [106.6, -6.0], [106.6, -6.4]],
+    [[106.75, -6.05], [106.76, -6.05], [106.76, -6.04], [106.75, -6.04], [106.75, -6.05]]
+  ]}},
+  {"type": "Feature", "properties": {}, "geometry": {"type": "MultiPolygon", "coordinates": [
+    [[[107.5, -7.0], [107.7, -7.0], [107.7, -6.8], [107.5, -6.8], [107.5, -7.0]]]
+  ]}}
+]}`
+
+func writeMask(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "mask.geojson")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadMask(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		polygons int
+		wantErr  bool
+	}{
+		{name: "feature collection", content: jakartaMask, polygons: 2},
+		{name: "bare polygon", content: `{"type": "Polygon", "coordinates": [[[0,0],[1,0],[1,1],[0,0]]]}`, polygons: 1},
+		{name: "geometry collection", content: `{"type": "GeometryCollection", "geometries": [{"type": "Polygon", "coordinates": [[[0,0],[1,0],[1,1],[0,0]]]}]}`, polygons: 1},
+		{name: "points only", content: `{"type": "Point", "coordinates": [0, 0]}`, wantErr: true},
+		{name: "bad coordinates", content: `{"type": "Polygon", "coordinates": [0, 0]}`, wantErr: true},
+		{name: "not json", content: `polygon`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		m, err := loadMask(writeMask(t, tt.content))
+		if tt.wantErr {
+			if !errors.Is(err, ErrBadInput) {
+				t.Errorf("%s: error = %v, want ErrBadInput", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || len(m) != tt.polygons {
+			t.Errorf("%s: %d polygons, %v, want %d", tt.name, len(m), err, tt.polygons)
+		}
+	}
+
+	if _, err := loadMask(filepath.Join(t.TempDir(), "missing.geojson")); !os.IsNotExist(err) {
+		t.Errorf("missing mask error = %v", err)
+	}
+}
+
+func TestOutsideMask(t *testing.T) {
+	m, err := loadMask(writeMask(t, jakartaMask))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ll := s2.LatLngFromDegrees
+	tests := []struct {
+		name   string
+		mask   mask
+		points []s2.LatLng
+		want   bool
+	}{
+		{name: "no mask", points: []s2.LatLng{ll(51.5, -0.1)}, want: false},
+		{name: "inside", mask: m, points: []s2.LatLng{ll(-6.3, 106.7), ll(-6.1, 106.9)}, want: false},
+		{name: "one end outside", mask: m, points: []s2.LatLng{ll(-6.3, 106.7), ll(-6.5, 106.7)}, want: true},
+		{name: "in the hole", mask: m, points: []s2.LatLng{ll(-6.045, 106.755)}, want: true},
+		{name: "second polygon", mask: m, points: []s2.LatLng{ll(-6.3, 106.7), ll(-6.9, 107.6)}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := outsideMask(tt.mask, tt.points...); got != tt.want {
+			t.Errorf("%s: outsideMask = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMarkLocationsMask(t *testing.T) {
+	m, err := loadMask(writeMask(t, jakartaMask))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := testOptions(t)
+	opts.mask = m
+	opts.filename = filepath.Join(t.TempDir(), "in.csv")
+	content := sampleCSV + `4,,,,,,,,,"-6.30,106.70",,,"-6.50,106.70"` + "\n"
+	if err := ioutil.WriteFile(opts.filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, sum, err := markLocations(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Routes != 3 || sum.Outside != 1 {
+		t.Errorf("%d routes, %d outside, want 3, 1", sum.Routes, sum.Outside)
+	}
+}
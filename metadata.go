@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/geo/s2"
+)
+
+// metadata is written as a JSON sidecar next to each rendered image so later
+// runs can reproduce or extend its framing.
+type metadata struct {
+	Input  string  `json:"input"`
+	Mode   string  `json:"mode"`
+	Rows   int     `json:"rows"`
+	Lat    float64 `json:"center_lat"`
+	Lng    float64 `json:"center_lng"`
+	Zoom   int     `json:"zoom"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+}
+
+// sidecarPath returns the metadata path for an image path.
+func sidecarPath(imagePath string) string {
+	return strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".json"
+}
+
+func (md metadata) viewport() Viewport {
+	return Viewport{
+		Center: s2.LatLngFromDegrees(md.Lat, md.Lng),
+		Zoom:   md.Zoom,
+		Width:  md.Width,
+		Height: md.Height,
+	}
+}
+
+func writeMetadata(imagePath string, md metadata) error {
+	data, err := json.MarshalIndent(md, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(sidecarPath(imagePath), data, 0644)
+}
+
+func readMetadata(imagePath string) (metadata, error) {
+	var md metadata
+
+	data, err := ioutil.ReadFile(sidecarPath(imagePath))
+	if err != nil {
+		return md, err
+	}
+
+	err = json.Unmarshal(data, &md)
+	return md, err
+}
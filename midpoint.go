@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/golang/geo/s2"
+)
+
+// midpoint returns the great-circle midpoint of a and b.
+func midpoint(a, b s2.LatLng) s2.LatLng {
+	return s2.LatLngFromPoint(s2.Interpolate(0.5, s2.PointFromLatLng(a), s2.PointFromLatLng(b)))
+}
+
+// writeMidpointCSV writes every plotted route's row with its great-circle
+// midpoint appended as mid_lat and mid_lng columns.
+func writeMidpointCSV(w io.Writer, lyr *layer) error {
+	if len(lyr.routes) == 0 {
+		return ErrTooFewRows
+	}
+
+	cw := csv.NewWriter(w)
+	if lyr.header != nil {
+		header := append(append([]string{}, lyr.header...), "mid_lat", "mid_lng")
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+
+	for _, rt := range lyr.routes {
+		mid := midpoint(rt.Src, rt.Dst)
+		record := append(append([]string{}, rt.Record...), formatDegrees(mid.Lat.Degrees()), formatDegrees(mid.Lng.Degrees()))
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
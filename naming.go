@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// flags that don't affect the rendered output and are left out of the hash
+var unhashedFlags = map[string]bool{
+	"audit-log":     true,
+	"deadline":      true,
+	"errors-out":    true,
+	"explain":       true,
+	"force":         true,
+	"manifest":      true,
+	"name-by-hash":  true,
+	"report":        true,
+	"retries":       true,
+	"skip-existing": true,
+	"spill":         true,
+	"spill-after":   true,
+	"verbose":       true,
+}
+
+// inputHash hashes the input file content together with every flag value,
+// so identical inputs and options always produce the same name.
+func inputHash(filename string, opts options) (string, error) {
+	h := sha256.New()
+
+	file, err := openSource(filename, opts)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// -audit-log hashes the content along
+	content := opts.hasher.reader(file)
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if err := opts.hasher.finish(content); err != nil {
+		return "", err
+	}
+
+	// VisitAll walks flags in lexicographical order
+	flag.VisitAll(func(f *flag.Flag) {
+		if !unhashedFlags[f.Name] {
+			fmt.Fprintf(h, "\x00%s=%s", f.Name, f.Value.String())
+		}
+	})
+
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// nameFields are the values a -name-template can use.
+type nameFields struct {
+	Base      string
+	Mode      string
+	RowCount  int
+	Timestamp int64
+	Width     int
+	Height    int
+}
+
+// parseNameTemplate parses a -name-template and checks it by executing it
+// on sample values, so mistakes fail at startup rather than after a
+// render.
+func parseNameTemplate(text string) (*template.Template, error) {
+	t, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: -name-template: %v", ErrBadInput, err)
+	}
+
+	name, err := executeName(t, nameFields{Base: "base", Mode: "plot", Timestamp: 1, Width: MapWidth, Height: MapHeight}, ".png")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(name, ".png") == "" {
+		return nil, fmt.Errorf("%w: -name-template %q gives an empty name", ErrBadInput, text)
+	}
+
+	return t, nil
+}
+
+// executeName renders the template to a file name, adding ext when it has
+// no extension.
+func executeName(t *template.Template, fields nameFields, ext string) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, fields); err != nil {
+		return "", fmt.Errorf("%w: -name-template: %v", ErrBadInput, err)
+	}
+
+	name := strings.TrimSpace(b.String())
+	if strings.Contains(name, "..") {
+		return "", fmt.Errorf("%w: -name-template gives %q, which leaves the output directory", ErrBadInput, name)
+	}
+	if filepath.Ext(name) == "" {
+		name += ext
+	}
+
+	return name, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import "fmt"
+
+// orderDetector infers once per file whether "x,y" cells are lat,lng or
+// lng,lat: a first valid cell whose would-be latitude is beyond ±90 can
+// only be lng,lat. Every later cell follows that decision.
+type orderDetector struct {
+	decided bool
+	swap    bool
+}
+
+// apply returns the pair in lat,lng order, deciding the order on the
+// first call. A nil detector, as with -no-order-detect, never swaps.
+func (d *orderDetector) apply(x, y float64) (float64, float64) {
+	if d == nil {
+		return x, y
+	}
+
+	if !d.decided {
+		d.decided = true
+		d.swap = (x < -90 || x > 90) && y >= -90 && y <= 90
+		if d.swap {
+			fmt.Println(fmt.Sprintf("Coordinate order: first cell %g,%g can't be lat,lng, reading every cell as lng,lat", x, y))
+		}
+	}
+
+	if d.swap {
+		return y, x
+	}
+
+	return x, y
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOrderDetector(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells [][2]float64
+		want  [][2]float64
+	}{
+		{
+			name:  "lat,lng kept",
+			cells: [][2]float64{{-6.2, 106.8}, {-6.1, 106.7}},
+			want:  [][2]float64{{-6.2, 106.8}, {-6.1, 106.7}},
+		},
+		{
+			name:  "lng,lat swapped",
+			cells: [][2]float64{{106.8, -6.2}, {106.7, -6.1}},
+			want:  [][2]float64{{-6.2, 106.8}, {-6.1, 106.7}},
+		},
+		{
+			name:  "ambiguous first cell decides lat,lng",
+			cells: [][2]float64{{1.5, 2.5}, {106.7, -6.1}},
+			want:  [][2]float64{{1.5, 2.5}, {106.7, -6.1}},
+		},
+		{
+			name:  "both out of latitude range kept",
+			cells: [][2]float64{{120, 130}},
+			want:  [][2]float64{{120, 130}},
+		},
+	}
+
+	for _, tt := range tests {
+		d := &orderDetector{}
+		for i, c := range tt.cells {
+			x, y := d.apply(c[0], c[1])
+			if x != tt.want[i][0] || y != tt.want[i][1] {
+				t.Errorf("%s: cell %d = %v,%v, want %v", tt.name, i, x, y, tt.want[i])
+			}
+		}
+	}
+
+	var off *orderDetector
+	if x, y := off.apply(106.8, -6.2); x != 106.8 || y != -6.2 {
+		t.Errorf("nil detector swapped to %v,%v", x, y)
+	}
+}
+
+func TestParseLocationOrder(t *testing.T) {
+	opts := testOptions(t)
+	opts.order = &orderDetector{}
+
+	x, y, err := parseLocation("106.8,-6.2", opts)
+	if err != nil || x != -6.2 || y != 106.8 {
+		t.Errorf("parseLocation = %v, %v, %v, want -6.2, 106.8", x, y, err)
+	}
+
+	opts.order = nil
+	if _, _, err := parseLocation("106.8,-6.2", opts); !errors.Is(err, ErrLatLongOutOfRange) {
+		t.Errorf("parseLocation without detection error = %v, want ErrLatLongOutOfRange", err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// outputPath joins name onto -outdir and creates the directories it
+// needs. With -safe the result, symlinks resolved, must stay inside
+// -outdir, so names that come from a request can't write elsewhere.
+func outputPath(name string, opts options) (string, error) {
+	p := filepath.Join(opts.outDir, name)
+	if opts.safe {
+		if err := checkWithin(p, opts.outDir); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return "", err
+	}
+	if opts.safe {
+		// MkdirAll may have walked through a symlink created meanwhile
+		if err := checkWithin(p, opts.outDir); err != nil {
+			return "", err
+		}
+	}
+
+	return p, nil
+}
+
+// checkWithin returns ErrBadInput unless p resolves to a path inside root.
+func checkWithin(p, root string) error {
+	absRoot, err := resolvePath(root)
+	if err != nil {
+		return err
+	}
+	absPath, err := resolvePath(p)
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: output %q is outside %q", ErrBadInput, p, root)
+	}
+
+	return nil
+}
+
+// resolvePath makes p absolute and resolves symlinks in the longest part of
+// it that exists; the rest is joined on as given, already cleaned.
+func resolvePath(p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+
+	existing, rest := abs, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(resolved, rest), nil
+}
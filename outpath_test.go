@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputPath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		safe    bool
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", file: "img.png", safe: true, want: filepath.Join(root, "img.png")},
+		{name: "subdirectory created", file: "a/b/img.png", safe: true, want: filepath.Join(root, "a", "b", "img.png")},
+		{name: "dot dot cleaned inside", file: "a/../img.png", safe: true, want: filepath.Join(root, "img.png")},
+		{name: "parent", file: "../img.png", safe: true, wantErr: true},
+		{name: "root itself", file: ".", safe: true, wantErr: true},
+		{name: "symlink out", file: "escape/img.png", safe: true, wantErr: true},
+		{name: "parent allowed without -safe", file: "../x/img.png", want: filepath.Join(filepath.Dir(root), "x", "img.png")},
+	}
+
+	for _, tt := range tests {
+		opts := testOptions(t)
+		opts.outDir = root
+		opts.safe = tt.safe
+
+		got, err := outputPath(tt.file, opts)
+		if tt.wantErr {
+			if !errors.Is(err, ErrBadInput) {
+				t.Errorf("%s: error = %v, want ErrBadInput", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: outputPath = %q, %v, want %q", tt.name, got, err, tt.want)
+			continue
+		}
+		if _, err := os.Stat(filepath.Dir(got)); err != nil {
+			t.Errorf("%s: directory not created: %v", tt.name, err)
+		}
+	}
+}
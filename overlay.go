@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"image"
+)
+
+// Corners overlays can be placed in
+const (
+	TopLeft     = "top-left"
+	TopRight    = "top-right"
+	BottomLeft  = "bottom-left"
+	BottomRight = "bottom-right"
+)
+
+// AttributionHeight is the strip go-staticmaps draws along the bottom of
+// tile renders for the tile provider attribution.
+const AttributionHeight = 16.0
+
+// Arrow size, in pixels
+const (
+	arrowW = 16.0
+	arrowH = 28.0
+)
+
+// checkCorner validates a corner flag value.
+func checkCorner(name string) error {
+	switch name {
+	case TopLeft, TopRight, BottomLeft, BottomRight:
+		return nil
+	}
+
+	return fmt.Errorf("%w: corner %q, expected %s, %s, %s or %s", ErrBadInput, name, TopLeft, TopRight, BottomLeft, BottomRight)
+}
+
+// cornerStack places overlays so those sharing a corner stack away from
+// its edge instead of covering each other. Values are the pixels already
+// taken from the top or bottom edge.
+type cornerStack map[string]float64
+
+// newCornerStack reserves the attribution strip of tile renders.
+func newCornerStack(attribution bool) cornerStack {
+	c := cornerStack{}
+	if attribution {
+		c[BottomLeft] = AttributionHeight
+		c[BottomRight] = AttributionHeight
+	}
+
+	return c
+}
+
+// place reserves a w×h box in corner of an imgW×imgH image and returns its
+// top-left position.
+func (c cornerStack) place(corner string, w, h float64, imgW, imgH int) (float64, float64) {
+	used := c[corner]
+	c[corner] = used + h + legendMargin
+
+	x := legendMargin
+	if corner == TopRight || corner == BottomRight {
+		x = float64(imgW) - legendMargin - w
+	}
+
+	y := legendMargin + used
+	if corner == BottomLeft || corner == BottomRight {
+		y = float64(imgH) - legendMargin - used - h
+	}
+
+	return x, y
+}
+
+// drawNorthArrow draws an arrow pointing up, the north of web mercator
+// renders, with an N above it.
+func drawNorthArrow(img image.Image, corner string, stack cornerStack, t theme) image.Image {
+	dc := newOverlay(img)
+
+	boxW, boxH := arrowW+2*legendPadding, arrowH+14+2*legendPadding
+	imgW, imgH := logicalSize(dc)
+	x, y := stack.place(corner, boxW, boxH, imgW, imgH)
+
+	dc.SetColor(t.Panel)
+	dc.DrawRectangle(x, y, boxW, boxH)
+	dc.Fill()
+
+	cx := x + boxW/2
+	top := y + legendPadding + 14
+	dc.SetColor(t.Text)
+	dc.DrawStringAnchored("N", cx, y+legendPadding+6, 0.5, 0.5)
+
+	dc.MoveTo(cx, top)
+	dc.LineTo(cx+arrowW/2, top+arrowH)
+	dc.LineTo(cx, top+arrowH*0.7)
+	dc.LineTo(cx-arrowW/2, top+arrowH)
+	dc.ClosePath()
+	dc.Fill()
+
+	return dc.Image()
+}
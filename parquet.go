@@ -0,0 +1,40 @@
+//go:build parquet
+// +build parquet
+
+package main
+
+import (
+	"io"
+
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// ParquetAvailable reports whether this build can write parquet mode.
+const ParquetAvailable = true
+
+// parquetRow is the schema of a parquet mode file, one row per plotted
+// route.
+type parquetRow struct {
+	Row        int64             `parquet:"name=row, type=INT64"`
+	SrcLat     float64           `parquet:"name=src_lat, type=DOUBLE"`
+	SrcLng     float64           `parquet:"name=src_lng, type=DOUBLE"`
+	DstLat     float64           `parquet:"name=dst_lat, type=DOUBLE"`
+	DstLng     float64           `parquet:"name=dst_lng, type=DOUBLE"`
+	DistanceKm float64           `parquet:"name=distance_km, type=DOUBLE"`
+	Columns    map[string]string `parquet:"name=columns, type=MAP, convertedtype=MAP, keytype=BYTE_ARRAY, keyconvertedtype=UTF8, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8"`
+}
+
+func encodeParquet(w io.Writer, rows []exportRow) error {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(parquetRow), 1)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rows {
+		if err := pw.Write(parquetRow(r)); err != nil {
+			return err
+		}
+	}
+
+	return pw.WriteStop()
+}
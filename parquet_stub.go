@@ -0,0 +1,15 @@
+//go:build !parquet
+// +build !parquet
+
+package main
+
+import "io"
+
+// ParquetAvailable reports whether this build can write parquet mode. The
+// writer pulls in a large dependency tree, so it is only built with
+// -tags parquet.
+const ParquetAvailable = false
+
+func encodeParquet(io.Writer, []exportRow) error {
+	return ErrNoParquet
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/geo/s2"
+)
+
+// DefaultRoles are the -role-values of long format input.
+const DefaultRoles = "source,destination"
+
+// roleValues are the -role-col cells marking a row as a route's source or
+// destination, compared without case.
+type roleValues struct {
+	Source, Destination string
+}
+
+func parseRoleValues(value string) (roleValues, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" ||
+		strings.EqualFold(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])) {
+		return roleValues{}, fmt.Errorf("%w: -role-values %q, expected two different values, e.g. %s", ErrBadInput, value, DefaultRoles)
+	}
+
+	return roleValues{Source: strings.TrimSpace(parts[0]), Destination: strings.TrimSpace(parts[1])}, nil
+}
+
+// pivotHalf is a long format route with only one of its rows read yet.
+type pivotHalf struct {
+	row      int
+	pos      s2.LatLng
+	isSource bool
+}
+
+// pivot reads a long format row, where a route's source and destination
+// are separate rows sharing an -id-col value and told apart by -role-col,
+// each with its location in -src-col. It returns the route once the ID
+// has both, in the row of whichever came second, and ready is false while
+// the other half hasn't been read.
+func (p *plotter) pivot(record []string, row int) (rt route, ready bool, err error) {
+	opts := p.opts
+	rt = route{Row: row, Record: record}
+
+	id, role := "", ""
+	if opts.idCol < len(record) {
+		id = strings.TrimSpace(record[opts.idCol])
+	}
+	if opts.roleCol < len(record) {
+		role = strings.TrimSpace(record[opts.roleCol])
+	}
+	if id == "" {
+		return rt, false, fmt.Errorf("%w: no -id-col value to pair the row by", ErrBadInput)
+	}
+
+	var isSource bool
+	switch {
+	case strings.EqualFold(role, opts.roles.Source):
+		isSource = true
+	case strings.EqualFold(role, opts.roles.Destination):
+	default:
+		return rt, false, fmt.Errorf("%w: role %q is neither %q nor %q", ErrBadInput, role, opts.roles.Source, opts.roles.Destination)
+	}
+
+	pos, err := locateColumn(record, opts.srcCol, opts.srcGeocodeCol, opts)
+	if err != nil {
+		if isSource {
+			return rt, false, &RowError{Row: row, Col: opts.srcCol, Err: &routeError{Src: err}}
+		}
+		return rt, false, &RowError{Row: row, Col: opts.srcCol, Err: &routeError{Dst: err}}
+	}
+
+	if p.halves == nil {
+		p.halves = map[string]pivotHalf{}
+	}
+	other, ok := p.halves[id]
+	if !ok {
+		p.halves[id] = pivotHalf{row: row, pos: pos, isSource: isSource}
+		return rt, false, nil
+	}
+	if other.isSource == isSource {
+		return rt, false, fmt.Errorf("%w: second %s row for ID %s, the first is row %d", ErrBadInput, role, id, other.row)
+	}
+
+	delete(p.halves, id)
+	rt.Src, rt.Dst = other.pos, pos
+	if isSource {
+		rt.Src, rt.Dst = pos, other.pos
+	}
+
+	return rt, true, nil
+}
+
+// unpaired reports the long format IDs that never got their other row,
+// leaving their rows out.
+func (p *plotter) unpaired() {
+	if len(p.halves) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(p.halves))
+	for id := range p.halves {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	missingSrc, missingDst := 0, 0
+	for _, id := range ids {
+		half := p.halves[id]
+		reason := "no destination row for its ID"
+		if half.isSource {
+			missingDst++
+		} else {
+			missingSrc++
+			reason = "no source row for its ID"
+		}
+		p.pass(half.row, reason)
+	}
+
+	shown := ids
+	if len(shown) > 10 {
+		shown = shown[:10]
+	}
+	more := ""
+	if len(ids) > len(shown) {
+		more = fmt.Sprintf(" and %d more", len(ids)-len(shown))
+	}
+	fmt.Println(fmt.Sprintf("Warning: %d IDs have only one of their two rows, %d without a source and %d without a destination: %s%s",
+		len(ids), missingSrc, missingDst, strings.Join(shown, ", "), more))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/bmishra/courierInfo/mapview"
+)
+
+// frame returns the viewport a render of the layer uses: the -base-image
+// sidecar's, or the one fitted to the data, or to its densest part with
+// -focus-percentile, with the -fixed-zoom and -center overrides applied
+// on top.
+func frame(lyr *layer, opts options) (Viewport, error) {
+	if opts.baseImage != "" {
+		md, err := readMetadata(opts.baseImage)
+		if err != nil {
+			return Viewport{}, err
+		}
+
+		return md.viewport(), nil
+	}
+
+	bounds := lyr.bounds()
+	if opts.focusPercentile > 0 {
+		bounds = focusBounds(markerPositions(lyr), opts.heatmapCell, opts.focusPercentile)
+	}
+
+	vp := mapview.FitViewport(bounds, MapWidth, MapHeight, lyr.margin())
+	if opts.fixedZoom > 0 {
+		vp.Zoom = opts.fixedZoom
+	}
+	if opts.center != nil {
+		vp.Center = *opts.center
+	}
+
+	return scaledViewport(vp), nil
+}
+
+// writePixelCSV writes every marker's ID, position and pixel coordinates
+// within an image rendered at vp. The pixels only hold for that exact
+// center, zoom and size.
+func writePixelCSV(w io.Writer, lyr *layer, vp Viewport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "lat", "lng", "x", "y"}); err != nil {
+		return err
+	}
+
+	for _, m := range lyr.markers {
+		x, y := vp.Project(m.Position)
+		record := []string{
+			lyr.ids[m],
+			formatDegrees(m.Position.Lat.Degrees()),
+			formatDegrees(m.Position.Lng.Degrees()),
+			strconv.FormatFloat(x, 'f', 2, 64),
+			strconv.FormatFloat(y, 'f', 2, 64),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatDegrees(v float64) string {
+	return strconv.FormatFloat(v, 'f', 6, 64)
+}
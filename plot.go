@@ -0,0 +1,465 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s2"
+)
+
+// route is a parsed row with both ends validated.
+type route struct {
+	Row    int
+	Record []string
+	File   string // input the row came from, see mergedReader.fileAt
+	Src    s2.LatLng
+	Dst    s2.LatLng
+}
+
+// routeError explains why a row's route was skipped. Either side is nil
+// when that coordinate was fine.
+type routeError struct {
+	Src error
+	Dst error
+}
+
+func (e *routeError) Error() string {
+	switch {
+	case e.Src != nil && e.Dst != nil:
+		return fmt.Sprintf("source: %v; destination: %v", e.Src, e.Dst)
+	case e.Src != nil:
+		return fmt.Sprintf("source: %v", e.Src)
+	}
+
+	return fmt.Sprintf("destination: %v", e.Dst)
+}
+
+func (e *routeError) Unwrap() []error {
+	var errs []error
+	for _, err := range []error{e.Src, e.Dst} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// parseRoute parses and validates both coordinates of a row. Both sides are
+// always checked so the error names every failing one.
+func parseRoute(record []string, row int, opts options) (route, error) {
+	rt := route{Row: row, Record: record}
+
+	var srcErr, dstErr error
+	if opts.origin != nil {
+		rt.Src = *opts.origin
+	} else {
+		rt.Src, srcErr = locateColumn(record, opts.srcCol, opts.srcGeocodeCol, opts)
+	}
+	rt.Dst, dstErr = locateColumn(record, opts.dstCol, opts.dstGeocodeCol, opts)
+
+	if srcErr != nil || dstErr != nil {
+		col := -1
+		if srcErr == nil {
+			col = opts.dstCol
+		} else if dstErr == nil && opts.origin == nil {
+			col = opts.srcCol
+		}
+		return rt, &RowError{Row: row, Col: col, Err: &routeError{Src: srcErr, Dst: dstErr}}
+	}
+
+	return rt, nil
+}
+
+// keepsRoutes reports whether the mode writes every plotted route after the
+// read, which the image modes don't, so they needn't hold them all.
+func keepsRoutes(opts options) bool {
+	return opts.mode == "midpoints" || opts.mode == "parquet"
+}
+
+// locateColumn is locate for a column that may be missing from the row.
+func locateColumn(record []string, col, geocodeCol int, opts options) (s2.LatLng, error) {
+	if col < 0 || col >= len(record) {
+		return s2.LatLng{}, fmt.Errorf("%w: column %d missing, row has %d columns", ErrLatLong, col, len(record))
+	}
+
+	x, y, err := locate(record, col, geocodeCol, opts)
+	if err != nil {
+		return s2.LatLng{}, err
+	}
+	x, y = opts.snapGrid.snap(x, y)
+
+	return s2.LatLngFromDegrees(x, y), nil
+}
+
+// plotter turns routes into markers and paths on a layer.
+type plotter struct {
+	opts   options
+	lyr    *layer
+	sum    *summary
+	header []string
+
+	groupColors map[string]color.RGBA
+	groupCounts map[string]int
+	groupOrder  []string
+
+	// -transport-col modes in first seen order
+	transportSeen   map[string]bool
+	transportLegend []legendEntry
+
+	// input of the row being read and the -file-colors legend
+	file       string
+	filesSeen  map[string]bool
+	fileLegend []legendEntry
+
+	// -id-col value per row number
+	rowIDs map[int]string
+
+	// rows kept by -allow-regions/-deny-regions, per code
+	regionCounts map[string]int
+
+	// rows kept and dropped by -range
+	ranged rangeCounts
+
+	// -size-col value per marker, absent when missing or non-numeric
+	sizeValues map[*sm.Marker]float64
+
+	// the row being read as it is in the input, for -errors-out
+	raw []string
+
+	// the trail chain mode is extending
+	chained chainState
+
+	// long format routes waiting for their other row, by -id-col value
+	halves map[string]pivotHalf
+}
+
+func newPlotter(opts options) *plotter {
+	lyr := &layer{}
+	if opts.mode == "line" {
+		lyr.dash = opts.dash
+	}
+
+	sum := &summary{}
+	if opts.mode == "stats" || opts.report != "" {
+		sum.Stats = newDistanceStats(opts.approxQuantiles)
+	}
+
+	return &plotter{
+		opts:        opts,
+		lyr:         lyr,
+		sum:         sum,
+		groupColors: map[string]color.RGBA{},
+		groupCounts: map[string]int{},
+	}
+}
+
+// addMarker adds m to the layer with the -size-col value of record, the
+// row it was drawn from. Markers without a row, or whose row has no number
+// there, keep their size.
+func (p *plotter) addMarker(m *sm.Marker, record []string) {
+	p.lyr.addMarker(m)
+
+	col := p.opts.sizeCol
+	if col < 0 || col >= len(record) {
+		return
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(record[col]), 64)
+	if err != nil || math.IsNaN(v) {
+		return
+	}
+	if p.sizeValues == nil {
+		p.sizeValues = map[*sm.Marker]float64{}
+	}
+	p.sizeValues[m] = v
+}
+
+func (p *plotter) group(record []string) string {
+	if p.opts.groupCol >= 0 && p.opts.groupCol < len(record) {
+		return record[p.opts.groupCol]
+	}
+
+	return ""
+}
+
+// groupFull reports whether the row's group has reached -limit-per-group.
+func (p *plotter) groupFull(record []string) bool {
+	return p.opts.groupCol >= 0 && p.opts.limitPerGroup > 0 && p.groupCounts[p.group(record)] >= p.opts.limitPerGroup
+}
+
+// skip records a skipped row.
+func (p *plotter) skip(rt route, err error) {
+	p.sum.Skipped++
+	p.sum.noteLeftOut(skipReason(err))
+	if p.opts.errorRows != nil {
+		p.opts.errorRows.add(p.raw, rowCause(err).Error())
+	}
+	if p.opts.verbose {
+		fmt.Println(fmt.Sprintf("%s: skipped, %v", p.rowLabel(rt.Row), rowCause(err)))
+	}
+	p.diagnose(routeDiag(rt, err))
+}
+
+// pass records a row left out on purpose, e.g. beyond -limit. It isn't
+// counted as skipped.
+func (p *plotter) pass(row int, reason string) {
+	p.sum.noteLeftOut(reason)
+	p.diagnose(diagRow{Row: row, Status: StatusSkipped, Reason: reason})
+}
+
+// add plots a route. It reports false once -max-markers is reached.
+func (p *plotter) add(rt route) bool {
+	opts := p.opts
+	lyr := p.lyr
+
+	if opts.countOnly {
+		p.sum.Routes++
+		return true
+	}
+
+	if opts.mode == "chain" {
+		return p.chain(rt)
+	}
+
+	// stats mode only needs the distance, not markers held in memory
+	if opts.mode == "stats" {
+		dist := distanceMeters(rt.Src, rt.Dst, opts.distanceModel)
+		p.diagnose(routeDiag(rt, nil))
+		p.sum.Routes++
+		p.sum.TotalDistance += dist
+		p.sum.Stats.add(dist / 1000)
+		return true
+	}
+
+	n := 2
+	if opts.midpoints {
+		n++
+	}
+	if markerCapReached(lyr, n, rt.Row, opts) {
+		return false
+	}
+
+	srcColor := opts.theme.Source
+	dstColor := opts.theme.Destination
+	if opts.groupCol >= 0 {
+		group := p.group(rt.Record)
+		c, ok := p.groupColors[group]
+		if !ok {
+			c, ok = opts.groupColors[group]
+			if !ok {
+				c = paletteColor(group)
+			}
+			p.groupColors[group] = c
+			p.groupOrder = append(p.groupOrder, group)
+		}
+		p.groupCounts[group]++
+		srcColor, dstColor = c, c
+	}
+	if opts.fileColors != nil {
+		c := p.noteFile(rt.File)
+		srcColor, dstColor = c, c
+	}
+
+	p.diagnose(routeDiag(rt, nil))
+	lyr.sources = append(lyr.sources, rt.Src)
+	if keepsRoutes(opts) {
+		lyr.routes = append(lyr.routes, rt)
+	}
+
+	dist := distanceMeters(rt.Src, rt.Dst, opts.distanceModel)
+	p.sum.Routes++
+	p.sum.TotalDistance += dist
+	if p.sum.Stats != nil {
+		p.sum.Stats.add(dist / 1000)
+	}
+
+	id := markerIDBase(rt.Record, rt.Row, opts)
+
+	glyph := ""
+	if opts.glyphCol >= 0 && opts.glyphCol < len(rt.Record) {
+		glyph = glyphOf(rt.Record[opts.glyphCol])
+	}
+
+	if opts.origin == nil {
+		src := sm.NewMarker(rt.Src, srcColor, opts.markerSize) //source
+		p.addMarker(src, rt.Record)
+		if opts.mode == "html" {
+			lyr.setProps(src, rowProps(p.header, rt.Record, rt.Row, "source", opts))
+		}
+		if glyph != "" {
+			lyr.setGlyph(src, glyph)
+		}
+		if opts.roleStyle == RoleFillHollow {
+			lyr.setHollow(src)
+		}
+		lyr.setID(src, id+".s")
+
+		if opts.bufferKm > 0 {
+			lyr.addArea(bufferArea(rt.Src, opts.bufferKm, srcColor, opts.bufferAlpha))
+		}
+	}
+	dst := sm.NewMarker(rt.Dst, dstColor, opts.markerSize) //destination
+	p.addMarker(dst, rt.Record)
+	if opts.mode == "html" {
+		lyr.setProps(dst, rowProps(p.header, rt.Record, rt.Row, "destination", opts))
+	}
+	if glyph != "" {
+		lyr.setGlyph(dst, glyph)
+	}
+	lyr.setID(dst, id+".d")
+
+	if opts.midpoints {
+		mid := sm.NewMarker(midpoint(rt.Src, rt.Dst), color.RGBA{0xff, 0xa5, 0x00, 0xff}, 0.6*opts.markerSize)
+		p.addMarker(mid, nil)
+		lyr.setID(mid, id+".m")
+	}
+
+	if opts.mode == "line" {
+		lineColor := opts.theme.Line
+		if opts.fileColors != nil {
+			lineColor = srcColor
+		}
+		if opts.colorByDistance {
+			lineColor = distanceColor(dist, opts.distanceMin, opts.distanceMax)
+		}
+
+		var dash []float64
+		style, styled := p.transport(rt.Record)
+		if styled {
+			lineColor = style.Color
+			dash = lineStyles[style.Style]
+		}
+
+		width := 1.0
+		if opts.widthByDistance {
+			width = widthForDistance(dist, opts.distanceMin, opts.distanceMax, opts.widthMin, opts.widthMax)
+		}
+
+		points := routePoints(rt.Src, rt.Dst, opts)
+
+		if opts.wrap {
+			for _, segment := range splitPolyline(points) {
+				path := sm.NewPath(segment, lineColor, width)
+				lyr.addPath(path)
+				if styled {
+					lyr.setDash(path, dash)
+				}
+			}
+		} else {
+			path := sm.NewPath(points, lineColor, width)
+			lyr.addPath(path)
+			if styled {
+				lyr.setDash(path, dash)
+			}
+		}
+	}
+
+	return true
+}
+
+// finish applies the whole-run adjustments once every route is added.
+func (p *plotter) finish() {
+	opts := p.opts
+
+	if opts.mode == "chain" {
+		p.flushChain()
+		fmt.Println(fmt.Sprintf("Trails: %d (%d distinct IDs), segments: %d, rows: %d",
+			p.chained.trails, len(p.chained.ids), p.chained.segments, p.sum.Routes))
+	}
+
+	// with -freq-size the repeat counts win: its merged markers are sized
+	// by multiplicity alone, not by a -size-col value of whichever row
+	// happened to come first
+	if opts.sizeCol >= 0 && !opts.freqSize {
+		scaleMarkerSizes(p.lyr.markers, p.sizeValues, opts.sizeMin, opts.sizeMax)
+	}
+
+	if opts.freqSize {
+		reportRepeats(mergeRepeats(p.lyr, opts.precision))
+	}
+
+	if opts.centroid != "" {
+		if c, ok := centroid(p.lyr.sources, opts.centroid); ok {
+			m := sm.NewMarker(c, color.RGBA{0x80, 0x00, 0x80, 0xff}, 1.5*opts.markerSize)
+			p.lyr.addMarker(m)
+			p.lyr.setID(m, "centroid")
+			p.sum.Centroid = &c
+		}
+	}
+
+	if opts.ellipseSigma > 0 {
+		if e, ok := standardEllipse(p.lyr.sources, opts.ellipseSigma); ok {
+			p.sum.Ellipse = e
+		}
+	}
+
+	if opts.origin != nil {
+		if opts.bufferKm > 0 {
+			p.lyr.addArea(bufferArea(*opts.origin, opts.bufferKm, opts.theme.Origin, opts.bufferAlpha))
+		}
+
+		origin := sm.NewMarker(*opts.origin, opts.theme.Origin, 2*opts.markerSize)
+		p.lyr.addMarker(origin)
+		p.lyr.setID(origin, "origin")
+	}
+
+	p.lyr.header = p.header
+	p.lyr.lineLegend = append(p.transportLegend, p.fileLegend...)
+	p.lyr.legend = append(p.lyr.legend, p.transportLegend...)
+	p.lyr.legend = append(p.lyr.legend, p.fileLegend...)
+
+	for _, group := range p.groupOrder {
+		p.lyr.legend = append(p.lyr.legend, legendEntry{Label: group, Color: p.groupColors[group]})
+	}
+
+	if opts.verbose && opts.groupCol >= 0 {
+		fmt.Println("Plotted per group:")
+		for _, group := range p.groupOrder {
+			fmt.Println(fmt.Sprintf("  %q: %d", group, p.groupCounts[group]))
+		}
+	}
+
+	if opts.verbose && opts.regions.active() {
+		p.printRegionCounts()
+	}
+
+	if len(opts.ranges) > 0 && !opts.countOnly {
+		p.printRangeCounts()
+	}
+
+	if opts.verbose {
+		fmt.Println(fmt.Sprintf("Skipped rows: %d", p.sum.Skipped))
+	}
+}
+
+// rowProps returns the attributes shown in a marker's popup: the row number,
+// its role, the -label-col value and every -popup-cols value keyed by its
+// header name.
+func rowProps(header, record []string, row int, role string, opts options) map[string]string {
+	props := map[string]string{
+		"row":  strconv.Itoa(row),
+		"role": role,
+	}
+
+	if opts.labelCol >= 0 && opts.labelCol < len(record) {
+		props["label"] = record[opts.labelCol]
+	}
+
+	for _, col := range opts.popupCols {
+		if col < 0 || col >= len(record) {
+			continue
+		}
+
+		name := fmt.Sprintf("col %d", col)
+		if col < len(header) && header[col] != "" {
+			name = header[col]
+		}
+		props[name] = record[col]
+	}
+
+	return props
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestMarkLocationsKeepsRoutes(t *testing.T) {
+	tests := []struct {
+		mode   string
+		routes int
+	}{
+		{mode: "plot", routes: 0},
+		{mode: "line", routes: 0},
+		{mode: "midpoints", routes: 3},
+		{mode: "parquet", routes: 3},
+	}
+
+	for _, tt := range tests {
+		opts := testOptions(t)
+		opts.mode = tt.mode
+		opts.filename = filepath.Join(t.TempDir(), "in.csv")
+		if err := ioutil.WriteFile(opts.filename, []byte(sampleCSV), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		lyr, sum, err := markLocations(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum.Routes != 3 || len(lyr.routes) != tt.routes {
+			t.Errorf("%s: %d routes plotted, %d kept, want 3, %d", tt.mode, sum.Routes, len(lyr.routes), tt.routes)
+		}
+	}
+}
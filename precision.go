@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+)
+
+// MetersPerDegree is the length of a degree of latitude, and of longitude
+// at the equator; a degree of longitude shrinks with cos(latitude).
+const MetersPerDegree = 111320.0
+
+// precision is the shared -precision used wherever nearby coordinates are
+// grouped: a number of decimals, or a distance in meters ("50m").
+type precision struct {
+	decimals int
+	meters   float64 // set for a distance precision
+}
+
+func parsePrecision(value string) (precision, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "m") {
+		m, err := strconv.ParseFloat(strings.TrimSuffix(value, "m"), 64)
+		if err != nil || m <= 0 {
+			return precision{}, fmt.Errorf("%w: precision %q, expected decimals or a positive distance like 50m", ErrBadInput, value)
+		}
+		return precision{decimals: 6, meters: m}, nil
+	}
+
+	d, err := strconv.Atoi(value)
+	if err != nil || d < 0 || d > 10 {
+		return precision{}, fmt.Errorf("%w: precision %q, expected 0 to 10 decimals or a distance like 50m", ErrBadInput, value)
+	}
+
+	return precision{decimals: d}, nil
+}
+
+func (p precision) String() string {
+	if p.meters > 0 {
+		return fmt.Sprintf("%gm", p.meters)
+	}
+
+	return strconv.Itoa(p.decimals)
+}
+
+// tolerance is the distance in meters within which two points are the
+// same. For decimals it is one unit of the last decimal at the equator,
+// e.g. about 1.1 m for 5 decimals.
+func (p precision) tolerance() float64 {
+	if p.meters > 0 {
+		return p.meters
+	}
+
+	return MetersPerDegree * math.Pow(10, -float64(p.decimals))
+}
+
+// round snaps ll to the grid the precision groups points by.
+func (p precision) round(ll s2.LatLng) (float64, float64) {
+	lat, lng := ll.Lat.Degrees(), ll.Lng.Degrees()
+	if p.meters == 0 {
+		return roundTo(lat, p.decimals), roundTo(lng, p.decimals)
+	}
+
+	latStep := p.meters / MetersPerDegree
+	lat = math.Round(lat/latStep) * latStep
+	lngStep := latStep / math.Max(math.Cos(lat*math.Pi/180), 1e-6)
+	lng = math.Round(lng/lngStep) * lngStep
+
+	return lat, lng
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// MercatorWarnRatio is the spread of Web Mercator area scale within a view
+// above which the render warns: features at its poleward edge look this
+// many times larger than equal ones at its equatorward edge.
+const MercatorWarnRatio = 2.0
+
+// mercatorAreaScale is how much Web Mercator enlarges areas at lat, in
+// degrees, relative to the equator: sec² of the latitude.
+func mercatorAreaScale(lat float64) float64 {
+	c := math.Cos(lat * math.Pi / 180)
+	return 1 / (c * c)
+}
+
+// mercatorDistortion returns the least and greatest area scale over the
+// latitudes vp shows.
+func mercatorDistortion(vp Viewport) (float64, float64) {
+	north := vp.Unproject(0, 0).Lat.Degrees()
+	south := vp.Unproject(0, float64(vp.Height)).Lat.Degrees()
+
+	least := math.Min(mercatorAreaScale(north), mercatorAreaScale(south))
+	if north > 0 && south < 0 {
+		least = 1
+	}
+
+	return least, math.Max(mercatorAreaScale(north), mercatorAreaScale(south))
+}
+
+// reportDistortion prints the view's Web Mercator area distortion, the only
+// projection go-staticmaps renders, with a warning when comparing areas
+// across the map would mislead.
+func reportDistortion(vp Viewport) {
+	least, most := mercatorDistortion(vp)
+	fmt.Println(fmt.Sprintf("Projection: Web Mercator, area scale %.2fx to %.2fx of the equator's", least, most))
+
+	if most/least > MercatorWarnRatio {
+		fmt.Println(fmt.Sprintf("Warning: the view spans latitudes where Web Mercator enlarges areas %.1fx more at one edge than the other; compare sizes and densities across it with care",
+			most/least))
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// numRange keeps the rows whose column Col is a number within Min and
+// Max, both inclusive; an open end is infinite.
+type numRange struct {
+	Col      int
+	Min, Max float64
+	spec     string
+}
+
+// rangeFilters is the -range flag, one range per use; a row must be
+// within all of them.
+type rangeFilters []numRange
+
+func (r *rangeFilters) String() string {
+	if r == nil {
+		return ""
+	}
+
+	specs := make([]string, len(*r))
+	for i, nr := range *r {
+		specs[i] = nr.spec
+	}
+	return strings.Join(specs, " ")
+}
+
+// Set parses "COL=MIN:MAX", "COL=MIN:" or "COL=:MAX".
+func (r *rangeFilters) Set(value string) error {
+	eq := strings.Index(value, "=")
+	if eq < 0 {
+		return fmt.Errorf("expected COL=MIN:MAX, got %q", value)
+	}
+	col, err := strconv.Atoi(strings.TrimSpace(value[:eq]))
+	if err != nil || col < 0 {
+		return fmt.Errorf("column %q of %q is not a column index", value[:eq], value)
+	}
+
+	bounds := strings.Split(value[eq+1:], ":")
+	if len(bounds) != 2 {
+		return fmt.Errorf("expected MIN:MAX after the column, got %q", value[eq+1:])
+	}
+
+	nr := numRange{Col: col, Min: math.Inf(-1), Max: math.Inf(1), spec: value}
+	for i, end := range []*float64{&nr.Min, &nr.Max} {
+		b := strings.TrimSpace(bounds[i])
+		if b == "" {
+			continue
+		}
+		if *end, err = strconv.ParseFloat(b, 64); err != nil {
+			return fmt.Errorf("bound %q of %q is not a number", b, value)
+		}
+	}
+	if nr.Min > nr.Max {
+		return fmt.Errorf("minimum above maximum in %q", value)
+	}
+
+	*r = append(*r, nr)
+	return nil
+}
+
+// rangeCounts tallies what -range did to the rows it saw.
+type rangeCounts struct {
+	passed, outside, nonNumeric int
+}
+
+// inRanges applies the -range filters to a row. A cell that is missing or
+// not a number is an error, the row skipped; one outside a range reports
+// false with the range.
+func inRanges(record []string, ranges rangeFilters) (bool, string, error) {
+	for _, nr := range ranges {
+		if nr.Col >= len(record) {
+			return false, "", fmt.Errorf("%w: -range column %d missing (%d columns)", ErrBadInput, nr.Col, len(record))
+		}
+		v, err := strconv.ParseFloat(normalizeDecimal(strings.TrimSpace(record[nr.Col])), 64)
+		if err != nil {
+			return false, "", fmt.Errorf("%w: -range column %d is %q, not a number", ErrBadInput, nr.Col, record[nr.Col])
+		}
+		if v < nr.Min || v > nr.Max {
+			return false, nr.spec, nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// rangeAllowed applies -range to a row, skipping and counting the ones
+// with no number to compare.
+func (p *plotter) rangeAllowed(record []string, row int) bool {
+	if len(p.opts.ranges) == 0 {
+		return true
+	}
+
+	ok, spec, err := inRanges(record, p.opts.ranges)
+	switch {
+	case err != nil:
+		p.ranged.nonNumeric++
+		p.skip(route{Row: row, Record: record}, err)
+	case !ok:
+		p.ranged.outside++
+		p.pass(row, "outside -range "+spec)
+	default:
+		p.ranged.passed++
+	}
+
+	return ok
+}
+
+func (p *plotter) printRangeCounts() {
+	fmt.Println(fmt.Sprintf("Range: %d rows passed -range, %d outside, %d non-numeric skipped",
+		p.ranged.passed, p.ranged.outside, p.ranged.nonNumeric))
+}
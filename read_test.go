@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sampleCSV has a header and three routes in the default columns.
+const sampleCSV = `order_id,a,b,c,d,e,f,g,h,seller_coordinates,i,j,buyer_coordinates
+1,,,,,,,,,"-6.38,106.88",,,"-6.13,106.78"
+2,,,,,,,,,"-6.20,106.80",,,"-6.10,106.70"
+3,,,,,,,,,"-6.30,106.90",,,"-6.25,106.85"
+`
+
+func TestMarkLocationsReadErrors(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(sampleCSV + strings.Repeat(sampleCSV[strings.Index(sampleCSV, "\n")+1:], 200)))
+	w.Close()
+
+	tests := []struct {
+		name    string
+		content []byte
+		ext     string
+		wantErr bool
+		routes  int
+	}{
+		{name: "plain", content: []byte(sampleCSV), ext: ".csv", routes: 3},
+		{name: "malformed row skipped", content: []byte(sampleCSV + "4,\"unclosed\n"), ext: ".csv", routes: 3},
+		{name: "truncated gzip", content: gz.Bytes()[:gz.Len()/2], ext: ".csv.gz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t)
+			opts.filename = filepath.Join(t.TempDir(), "in"+tt.ext)
+			if err := os.WriteFile(opts.filename, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, sum, err := markLocations(opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum.Routes != tt.routes {
+				t.Errorf("%d routes, want %d", sum.Routes, tt.routes)
+			}
+		})
+	}
+}
+
+func TestMarkLocationsCSVOptions(t *testing.T) {
+	const strayQuote = `4,say "hi",,,,,,,,"-6.20,106.80",,,"-6.10,106.70"` + "\n"
+
+	tests := []struct {
+		name       string
+		content    string
+		comment    rune
+		lazyQuotes bool
+		rows       int
+		routes     int
+	}{
+		{name: "comment skipped", content: "# exported today\n" + sampleCSV + "# 3 rows\n", comment: '#', rows: 3, routes: 3},
+		{name: "custom comment", content: sampleCSV + "; 3 rows\n", comment: ';', rows: 3, routes: 3},
+		{name: "comments disabled", content: sampleCSV + "# 3 rows\n", rows: 4, routes: 3},
+		{name: "stray quote skipped", content: sampleCSV + strayQuote, comment: '#', rows: 4, routes: 3},
+		{name: "lazy quotes", content: sampleCSV + strayQuote, comment: '#', lazyQuotes: true, rows: 4, routes: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t)
+			opts.comment = tt.comment
+			opts.lazyQuotes = tt.lazyQuotes
+			opts.noPrecheck = true
+			opts.filename = filepath.Join(t.TempDir(), "in.csv")
+			if err := os.WriteFile(opts.filename, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, sum, err := markLocations(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum.RowCount != tt.rows || sum.Routes != tt.routes {
+				t.Errorf("%d rows, %d routes, want %d, %d", sum.RowCount, sum.Routes, tt.rows, tt.routes)
+			}
+		})
+	}
+}
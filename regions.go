@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// regionFilter keeps or drops rows by the ISO country or state code in
+// -region-col. Codes compare case-insensitively.
+type regionFilter struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+// parseRegionList parses a comma separated list of codes such as
+// "IN,NP" or "IN-KA,IN-TN".
+func parseRegionList(value string) map[string]bool {
+	codes := map[string]bool{}
+	for _, code := range strings.Split(value, ",") {
+		if code = normalizeRegion(code); code != "" {
+			codes[code] = true
+		}
+	}
+
+	if len(codes) == 0 {
+		return nil
+	}
+
+	return codes
+}
+
+func normalizeRegion(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// active reports whether any list is set.
+func (f regionFilter) active() bool {
+	return len(f.allow) > 0 || len(f.deny) > 0
+}
+
+// allowed reports whether code passes the filter. With an allowlist only
+// the listed codes pass; the denylist then removes codes from what's left.
+func (f regionFilter) allowed(code string) bool {
+	code = normalizeRegion(code)
+	if len(f.allow) > 0 && !f.allow[code] {
+		return false
+	}
+
+	return !f.deny[code]
+}
+
+// region returns the row's -region-col value.
+func (p *plotter) region(record []string) string {
+	if p.opts.regionCol >= 0 && p.opts.regionCol < len(record) {
+		return normalizeRegion(record[p.opts.regionCol])
+	}
+
+	return ""
+}
+
+// regionAllowed applies the region filter to a row and counts the rows it
+// keeps.
+func (p *plotter) regionAllowed(record []string) bool {
+	if !p.opts.regions.active() {
+		return true
+	}
+
+	code := p.region(record)
+	if !p.opts.regions.allowed(code) {
+		return false
+	}
+
+	if p.regionCounts == nil {
+		p.regionCounts = map[string]int{}
+	}
+	p.regionCounts[code]++
+	return true
+}
+
+// printRegionCounts prints the retained rows per region, busiest first.
+func (p *plotter) printRegionCounts() {
+	codes := make([]string, 0, len(p.regionCounts))
+	for code := range p.regionCounts {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if p.regionCounts[codes[i]] != p.regionCounts[codes[j]] {
+			return p.regionCounts[codes[i]] > p.regionCounts[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+
+	fmt.Println("Retained per region:")
+	for _, code := range codes {
+		fmt.Println(fmt.Sprintf("  %q: %d", code, p.regionCounts[code]))
+	}
+}
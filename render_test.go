@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmishra/courierInfo/mapview"
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/fogleman/gg"
+	"github.com/golang/geo/s2"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden images of the render tests")
+
+// goldenTolerance is how far a channel may be off before the pixel counts
+// as changed, and goldenMaxChanged how many changed pixels are allowed, for
+// rounding differences in antialiased edges. A recolored or moved marker
+// changes far more.
+const (
+	goldenTolerance  = 2
+	goldenMaxChanged = 8
+)
+
+// goldenCenter and goldenZoom fix the view of the render tests.
+var goldenCenter = s2.LatLngFromDegrees(-6.2, 106.82)
+
+const goldenZoom = 11
+
+// stubTiles serves a checkerboard of numbered gray tiles from a local
+// server and registers it as a tile provider, returning its name.
+func stubTiles(t *testing.T) string {
+	t.Helper()
+
+	// go-staticmaps caches tiles under the user cache directory
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("TMPDIR", t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var z, x, y int
+		if _, err := fmt.Sscanf(r.URL.Path, "/%d/%d/%d.png", &z, &x, &y); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		tile := image.NewRGBA(image.Rect(0, 0, 256, 256))
+		shade := uint8(0xd0)
+		if (x+y)%2 == 0 {
+			shade = 0xe8
+		}
+		draw.Draw(tile, tile.Bounds(), image.NewUniform(color.RGBA{shade, shade, shade, 0xff}), image.Point{}, draw.Src)
+		for i := 0; i < 256; i++ {
+			tile.Set(i, 0, color.Black)
+			tile.Set(0, i, color.Black)
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		png.Encode(w, tile)
+	}))
+	t.Cleanup(srv.Close)
+
+	name := "test-stub"
+	localTileProviders[name] = &sm.TileProvider{Name: name, TileSize: 256, URLPattern: srv.URL + "/%[2]d/%[3]d/%[4]d.png"}
+	t.Cleanup(func() { delete(localTileProviders, name) })
+
+	return name
+}
+
+// renderRoutes renders testdata/render/routes.csv at the golden view,
+// without tiles unless setup picks a provider.
+func renderRoutes(t *testing.T, setup func(*options)) image.Image {
+	t.Helper()
+
+	opts := testOptions(t)
+	opts.filename = filepath.Join("testdata", "render", "routes.csv")
+	opts.noBasemap = true
+	center := goldenCenter
+	opts.center = &center
+	opts.fixedZoom = goldenZoom
+	opts.imageFormat = FormatPNG
+	opts.output = filepath.Join(t.TempDir(), "out.png")
+	setup(&opts)
+
+	if _, err := runFile(opts); err != nil {
+		t.Fatal(err)
+	}
+	img, err := gg.LoadPNG(opts.output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
+
+// TestRenderGolden renders testdata/render/routes.csv at a fixed center and
+// zoom, without tiles or over a local stub tile server so it runs offline,
+// and compares the PNG against the checked-in golden image. Run with
+// -update after an intended change.
+func TestRenderGolden(t *testing.T) {
+	tiles := stubTiles(t)
+
+	tests := []struct {
+		name  string
+		setup func(*options)
+	}{
+		{name: "plot", setup: func(o *options) {}},
+		{name: "plot-circles", setup: func(o *options) { o.markerStyle = MarkerCircle }},
+		{name: "line", setup: func(o *options) { o.mode = "line" }},
+		{name: "line-dark", setup: func(o *options) {
+			o.mode = "line"
+			o.theme, _ = parseTheme("dark")
+		}},
+		{name: "plot-tiles", setup: func(o *options) {
+			o.noBasemap = false
+			o.tiles = tiles
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderRoutes(t, tt.setup)
+
+			golden := filepath.Join("testdata", "render", tt.name+".png")
+			if *updateGolden {
+				if err := gg.SavePNG(golden, got); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := loadPNG(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -run TestRenderGolden -update to create it)", err)
+			}
+			if changed, total, ok := compareImages(got, want); !ok {
+				t.Errorf("%d of %d pixels differ from %s", changed, total, golden)
+			}
+		})
+	}
+}
+
+// TestRenderGoldenCatchesChanges re-renders the plot golden with a changed
+// marker color and with every marker moved 3px, and checks that the
+// comparison fails for both.
+func TestRenderGoldenCatchesChanges(t *testing.T) {
+	want, err := loadPNG(filepath.Join("testdata", "render", "plot.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 3px of longitude at goldenZoom
+	shift := 3 * 360 / (mapview.TileSize * math.Exp2(goldenZoom))
+
+	tests := []struct {
+		name  string
+		setup func(*options)
+	}{
+		{name: "source color", setup: func(o *options) { o.theme.Source = color.RGBA{0x00, 0x00, 0xff, 0xff} }},
+		{name: "3px offset", setup: func(o *options) {
+			center := s2.LatLngFromDegrees(goldenCenter.Lat.Degrees(), goldenCenter.Lng.Degrees()+shift)
+			o.center = &center
+		}},
+	}
+
+	for _, tt := range tests {
+		got := renderRoutes(t, tt.setup)
+		if changed, total, ok := compareImages(got, want); ok {
+			t.Errorf("%s: only %d of %d pixels differ, the golden comparison missed the change", tt.name, changed, total)
+		}
+	}
+}
+
+func loadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+// compareImages counts the pixels of got further than goldenTolerance from
+// want in any channel.
+func compareImages(got, want image.Image) (int, int, bool) {
+	gb, wb := got.Bounds(), want.Bounds()
+	total := wb.Dx() * wb.Dy()
+	if gb.Size() != wb.Size() {
+		return total, total, false
+	}
+
+	changed := 0
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			r1, g1, b1, a1 := got.At(gb.Min.X+x, gb.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := want.At(wb.Min.X+x, wb.Min.Y+y).RGBA()
+			for _, d := range []int{int(r1>>8) - int(r2>>8), int(g1>>8) - int(g2>>8), int(b1>>8) - int(b2>>8), int(a1>>8) - int(a2>>8)} {
+				if d > goldenTolerance || d < -goldenTolerance {
+					changed++
+					break
+				}
+			}
+		}
+	}
+
+	return changed, total, changed <= goldenMaxChanged
+}
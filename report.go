@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// skipReason groups a row error for the -report skip breakdown.
+func skipReason(err error) string {
+	switch {
+	case errors.Is(err, ErrLatLongOutOfRange):
+		return "coordinates out of range"
+	case errors.Is(err, ErrLatLong):
+		return "invalid or missing coordinates"
+	case errors.Is(err, ErrNotFound):
+		return "address not found by the geocoder"
+	}
+
+	return "unreadable row"
+}
+
+// noteLeftOut counts a row left out of the map under reason.
+func (s *summary) noteLeftOut(reason string) {
+	if s.LeftOut == nil {
+		s.LeftOut = map[string]int{}
+	}
+	s.LeftOut[reason]++
+}
+
+// writeReport writes a Markdown summary of the run for a PR or wiki page:
+// the changed flags, then per input its counts, why rows were left out,
+// the distance stats and the output, linked relative to reportPath.
+func writeReport(w io.Writer, reportPath string, files []string, results []*fileResult, opts options) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# courierInfo run, mode %s\n\n", opts.mode)
+	fmt.Fprintf(&b, "Generated %s.\n\n", time.Now().Format(time.RFC1123))
+
+	b.WriteString("## Parameters\n\n")
+	b.WriteString("| Flag | Value | Source |\n|---|---|---|\n")
+	for _, s := range effectiveSettings() {
+		if s.Source != SourceDefault {
+			fmt.Fprintf(&b, "| -%s | %s | %s |\n", s.Name, markdownCell(s.Value), s.Source)
+		}
+	}
+
+	for i, res := range results {
+		fmt.Fprintf(&b, "\n## %s\n\n", markdownCell(files[i]))
+
+		if res.Skipped {
+			b.WriteString("Up to date, not rendered again.\n")
+		}
+
+		sum := res.Summary
+		if sum != nil {
+			fmt.Fprintf(&b, "- Rows: %d\n- Routes plotted: %d\n- Rows skipped: %d\n", sum.RowCount, sum.Routes, sum.Skipped)
+			if sum.Routes > 0 {
+				fmt.Fprintf(&b, "- Total distance: %.3f km, mean %.3f km (%s)\n",
+					sum.TotalDistance/1000, sum.TotalDistance/1000/float64(sum.Routes), opts.distanceModel)
+			}
+			if sum.Centroid != nil {
+				fmt.Fprintf(&b, "- Centroid (%s): %f,%f\n", opts.centroid, sum.Centroid.Lat.Degrees(), sum.Centroid.Lng.Degrees())
+			}
+
+			if len(sum.LeftOut) > 0 {
+				b.WriteString("\n| Left out because | Rows |\n|---|---|\n")
+				var reasons []string
+				for reason := range sum.LeftOut {
+					reasons = append(reasons, reason)
+				}
+				sort.Strings(reasons)
+				for _, reason := range reasons {
+					fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(reason), sum.LeftOut[reason])
+				}
+			}
+
+			if sum.Stats != nil && sum.Stats.count > 0 {
+				r := sum.Stats.report()
+				fmt.Fprintf(&b, "\nDistance min %.3f km, max %.3f km.\n\n| Quantile | km |\n|---|---|\n", r.MinKm, r.MaxKm)
+				for _, q := range r.Quantiles {
+					v := q.Exact
+					if v == nil {
+						v = q.Estimate
+					}
+					fmt.Fprintf(&b, "| p%g | %.3f |\n", 100*q.Quantile, *v)
+				}
+			}
+		}
+
+		if md := res.Metadata; md != nil {
+			fmt.Fprintf(&b, "\nView: center %f,%f, zoom %d, %dx%d.\n", md.Lat, md.Lng, md.Zoom, md.Width, md.Height)
+		}
+
+		if res.Path != "" {
+			link := res.Path
+			if rel, err := filepath.Rel(filepath.Dir(reportPath), res.Path); err == nil {
+				link = filepath.ToSlash(rel)
+			}
+			if res.Metadata != nil {
+				fmt.Fprintf(&b, "\n![%s](%s)\n", markdownCell(filepath.Base(res.Path)), link)
+			} else {
+				fmt.Fprintf(&b, "\nOutput: [%s](%s)\n", markdownCell(filepath.Base(res.Path)), link)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapes the characters that would break a table cell or
+// start markup.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ", "[", `\[`, "]", `\]`).Replace(s)
+}
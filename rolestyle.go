@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/fogleman/gg"
+)
+
+// RoleFillHollow is the -role-style drawing sources as rings and
+// destinations as filled circles.
+const RoleFillHollow = "fill-hollow"
+
+func checkRoleStyle(style, markerStyle string) error {
+	if style != RoleFillHollow {
+		return fmt.Errorf("%w: -role-style %q, expected %s", ErrBadInput, style, RoleFillHollow)
+	}
+	if markerStyle == MarkerPin {
+		return fmt.Errorf("%w: -role-style %s draws circles, it can't be used with -marker-style %s", ErrBadInput, style, MarkerPin)
+	}
+
+	return nil
+}
+
+func (l *layer) setHollow(m *sm.Marker) {
+	if l.spilled(m) {
+		return
+	}
+	if l.hollow == nil {
+		l.hollow = map[*sm.Marker]bool{}
+	}
+	l.hollow[m] = true
+}
+
+// hollowMarkers returns the markers drawLayer draws as rings, in the order
+// they were added.
+func (l *layer) hollowMarkers() []*sm.Marker {
+	var markers []*sm.Marker
+	for _, m := range l.markers {
+		if l.hollow[m] {
+			markers = append(markers, m)
+		}
+	}
+
+	return markers
+}
+
+// drawHollowMarker draws m as a ring in its color, a quarter of its size
+// wide, edged in black like the filled circles so both read the same on
+// any basemap.
+func drawHollowMarker(dc *gg.Context, m *sm.Marker, vp Viewport) {
+	x, y := vp.Project(m.Position)
+	r := 0.5 * m.Size
+	w := 0.25 * m.Size
+
+	dc.ClearPath()
+	dc.DrawCircle(x, y, r-w/2)
+	dc.SetColor(m.Color)
+	dc.SetLineWidth(w)
+	dc.Stroke()
+
+	dc.DrawCircle(x, y, r)
+	dc.SetRGB(0, 0, 0)
+	dc.SetLineWidth(1.0)
+	dc.Stroke()
+}
@@ -0,0 +1,12 @@
+package main
+
+import "github.com/bmishra/courierInfo/rowerror"
+
+// RowError is why an input row was skipped; see rowerror.RowError.
+type RowError = rowerror.RowError
+
+// rowCause strips the RowError off err, for messages that name the row
+// already.
+func rowCause(err error) error {
+	return rowerror.Cause(err)
+}
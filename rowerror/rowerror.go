@@ -0,0 +1,42 @@
+// Package rowerror carries the row and column an input row was skipped for,
+// so callers embedding the plotter can report bad rows themselves.
+package rowerror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RowError is why an input row was skipped: its row number, the column at
+// fault when one is, and the cause. It unwraps to the cause, so
+// errors.Is still matches the sentinel behind it, such as the plotter's
+// ErrLatLongOutOfRange, and errors.As finds the RowError itself to read
+// the row.
+type RowError struct {
+	Row int
+	Col int // -1 when the row fails as a whole, or on both ends
+	Err error
+}
+
+func (e *RowError) Error() string {
+	if e.Col < 0 {
+		return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+	}
+
+	return fmt.Sprintf("row %d, column %d: %v", e.Row, e.Col, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Cause strips the RowError off err, for messages that name the row
+// already.
+func Cause(err error) error {
+	var re *RowError
+	if errors.As(err, &re) {
+		return re.Err
+	}
+
+	return err
+}
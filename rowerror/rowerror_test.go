@@ -0,0 +1,44 @@
+package rowerror
+
+import (
+	"errors"
+	"testing"
+)
+
+var (
+	errBad   = errors.New("bad")
+	errRange = errors.New("out of range")
+)
+
+func TestRowError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *RowError
+		want string
+	}{
+		{name: "whole row", err: &RowError{Row: 3, Col: -1, Err: errBad}, want: "row 3: bad"},
+		{name: "one column", err: &RowError{Row: 4, Col: 9, Err: errRange}, want: "row 4, column 9: out of range"},
+	}
+
+	for _, tt := range tests {
+		var err error = tt.err
+		if got := err.Error(); got != tt.want {
+			t.Errorf("%s: Error() = %q, want %q", tt.name, got, tt.want)
+		}
+		if !errors.Is(err, tt.err.Err) {
+			t.Errorf("%s: errors.Is(err, %v) = false", tt.name, tt.err.Err)
+		}
+
+		var re *RowError
+		if !errors.As(err, &re) || re.Row != tt.err.Row {
+			t.Errorf("%s: errors.As found %v", tt.name, re)
+		}
+		if got := Cause(err); got != tt.err.Err {
+			t.Errorf("%s: Cause = %v, want %v", tt.name, got, tt.err.Err)
+		}
+	}
+
+	if got := Cause(errBad); got != errBad {
+		t.Errorf("Cause of a plain error = %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRouteRowError(t *testing.T) {
+	row := func(src, dst string) []string {
+		r := make([]string, 13)
+		r[9], r[12] = src, dst
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		record []string
+		col    int
+		is     []error
+	}{
+		{name: "bad source", record: row("x", "-6.1,106.7"), col: 9, is: []error{ErrLatLong}},
+		{name: "destination out of range", record: row("-6.2,106.8", "51.5,-0.1"), col: 12, is: []error{ErrLatLongOutOfRange}},
+		{name: "both ends", record: row("", "51.5,-0.1"), col: -1, is: []error{ErrLatLong, ErrLatLongOutOfRange}},
+	}
+
+	for _, tt := range tests {
+		_, err := parseRoute(tt.record, 7, testOptions(t))
+
+		var re *RowError
+		if !errors.As(err, &re) {
+			t.Errorf("%s: error %v is not a RowError", tt.name, err)
+			continue
+		}
+		if re.Row != 7 || re.Col != tt.col {
+			t.Errorf("%s: row %d, column %d, want 7, %d", tt.name, re.Row, re.Col, tt.col)
+		}
+		for _, target := range tt.is {
+			if !errors.Is(err, target) {
+				t.Errorf("%s: errors.Is(err, %v) = false for %v", tt.name, target, err)
+			}
+		}
+	}
+
+	if _, err := parseRoute(row("-6.2,106.8", "-6.1,106.7"), 7, testOptions(t)); err != nil {
+		t.Errorf("valid route: %v", err)
+	}
+}
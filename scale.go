@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/fogleman/gg"
+)
+
+// pixelScale is the -scale pixel density: the image is rendered at this
+// many pixels per logical pixel, one zoom level up per doubling, so it
+// shows the same view as a 1x render, only sharper.
+var pixelScale = 1.0
+
+// scaleSuffix marks default image names of scaled renders, "@2x" as
+// asset pipelines expect.
+func scaleSuffix() string {
+	if pixelScale == 1 {
+		return ""
+	}
+
+	return fmt.Sprintf("@%gx", pixelScale)
+}
+
+func checkScale(s int) error {
+	switch s {
+	case 1, 2, 4:
+		return nil
+	}
+
+	return fmt.Errorf("%w: -scale %d, expected 1, 2 or 4", ErrBadInput, s)
+}
+
+// scaledViewport is the view at pixelScale: the same center and extent in
+// more pixels.
+func scaledViewport(v Viewport) Viewport {
+	v.Width = int(float64(v.Width) * pixelScale)
+	v.Height = int(float64(v.Height) * pixelScale)
+	v.Zoom += int(math.Log2(pixelScale))
+	return v
+}
+
+// scaleLayer grows the layer's markers, lines and dash patterns to
+// pixelScale, just before the render. Later renders of the same layer,
+// as in zooms mode, find it scaled already.
+func scaleLayer(l *layer) {
+	if pixelScale == 1 || l.scaled {
+		return
+	}
+	l.scaled = true
+
+	for _, m := range l.markers {
+		m.Size *= pixelScale
+	}
+	for _, p := range l.paths {
+		p.Weight *= pixelScale
+	}
+	for _, a := range l.areas {
+		a.Weight *= pixelScale
+	}
+
+	l.dash = scaleDash(l.dash)
+	for p, d := range l.pathDash {
+		l.pathDash[p] = scaleDash(d)
+	}
+}
+
+func scaleDash(dash []float64) []float64 {
+	out := make([]float64, len(dash))
+	for i, d := range dash {
+		out[i] = d * pixelScale
+	}
+
+	return out
+}
+
+// newOverlay returns a context drawing on img in logical pixels, for the
+// corner overlays: coordinates are scaled by pixelScale and the text
+// overlay font is loaded at the scaled size. gg doesn't scale line widths,
+// so those are multiplied by pixelScale where they are set.
+func newOverlay(img image.Image) *gg.Context {
+	dc := useTextFont(gg.NewContextForImage(img))
+	dc.Scale(pixelScale, pixelScale)
+	return dc
+}
+
+// logicalSize is the size of dc's image in logical pixels.
+func logicalSize(dc *gg.Context) (int, int) {
+	return int(float64(dc.Width()) / pixelScale), int(float64(dc.Height()) / pixelScale)
+}
+
+// measureString measures s in logical pixels.
+func measureString(dc *gg.Context, s string) (float64, float64) {
+	w, h := dc.MeasureString(s)
+	return w / pixelScale, h / pixelScale
+}
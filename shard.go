@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+)
+
+// shardReader yields the input's header lines followed by the lines that
+// start within [start, end) bytes, so workers given adjacent ranges
+// together read every line exactly once. A line that starts before start
+// belongs to the previous shard, one that starts at or after end to the
+// next; end 0 reads to the end of the input.
+//
+// Quoted fields spanning several lines are not detected, so shard edges
+// may split such records.
+type shardReader struct {
+	br  *bufio.Reader
+	pos int64 // input offset of the next byte in br
+	end int64
+	buf []byte
+}
+
+func newShardReader(r io.Reader, start, end int64, headerRows int) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	var header []byte
+	for i := 0; i < headerRows; i++ {
+		line, err := br.ReadBytes('\n')
+		header = append(header, line...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	s := &shardReader{br: br, pos: int64(len(header)), end: end, buf: header}
+
+	if s.pos < start {
+		// stop one byte short to see whether start begins a line
+		if seeker, ok := r.(io.Seeker); ok {
+			if _, err := seeker.Seek(start-1, io.SeekStart); err != nil {
+				return nil, err
+			}
+			br.Reset(r)
+		} else if _, err := io.CopyN(ioutil.Discard, br, start-1-s.pos); err != nil {
+			return s, nil
+		}
+		s.pos = start - 1
+
+		b, err := br.ReadByte()
+		if err != nil {
+			return s, nil
+		}
+		s.pos++
+
+		if b != '\n' {
+			partial, _ := br.ReadBytes('\n')
+			s.pos += int64(len(partial))
+		}
+	}
+
+	return s, nil
+}
+
+func (s *shardReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.end > 0 && s.pos >= s.end {
+			return 0, io.EOF
+		}
+
+		line, err := s.br.ReadBytes('\n')
+		s.pos += int64(len(line))
+		s.buf = line
+		if err != nil {
+			if len(line) == 0 {
+				return 0, err
+			}
+			break
+		}
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+
+	"github.com/fogleman/gg"
+)
+
+// CaptionHeight is the height of the caption strip below each sheet tile.
+const CaptionHeight = 20
+
+// writeContactSheet tiles the rendered images of results into one PNG,
+// columns wide, each captioned with its file name and row count. Results
+// without an image (skipped or non-image modes) are left out.
+func writeContactSheet(path string, files []string, results []*fileResult, columns int) error {
+	if columns < 1 {
+		return ErrBadInput
+	}
+
+	var tiles []image.Image
+	var captions []string
+	for i, res := range results {
+		if res == nil || res.Image == nil {
+			continue
+		}
+		tiles = append(tiles, res.Image)
+		captions = append(captions, fmt.Sprintf("%s (%d rows)", filepath.Base(files[i]), res.Summary.RowCount))
+	}
+	if len(tiles) == 0 {
+		return ErrTooFewRows
+	}
+
+	cellW, cellH := 0, 0
+	for _, t := range tiles {
+		if b := t.Bounds(); b.Dx() > cellW {
+			cellW = b.Dx()
+		}
+		if b := t.Bounds(); b.Dy() > cellH {
+			cellH = b.Dy()
+		}
+	}
+	cellH += CaptionHeight
+
+	if columns > len(tiles) {
+		columns = len(tiles)
+	}
+	rows := (len(tiles) + columns - 1) / columns
+
+	dc := useTextFont(gg.NewContext(columns*cellW, rows*cellH))
+	dc.SetRGB(1, 1, 1)
+	dc.Clear()
+
+	for i, t := range tiles {
+		x := (i % columns) * cellW
+		y := (i / columns) * cellH
+		dc.DrawImage(t, x, y)
+
+		dc.SetRGB(0, 0, 0)
+		dc.DrawStringAnchored(captions[i], float64(x+cellW/2), float64(y+cellH-CaptionHeight/2), 0.5, 0.5)
+	}
+
+	return dc.SavePNG(path)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+)
+
+// ExitInterrupted is the exit status after an interrupted run.
+const ExitInterrupted = 130
+
+var interrupted int32
+
+// handleInterrupts makes the first SIGINT/SIGTERM stop parsing so whatever
+// has been read so far is still rendered and written. A second signal exits
+// immediately.
+func handleInterrupts() {
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-ch
+		atomic.StoreInt32(&interrupted, 1)
+		fmt.Println("\nInterrupted, writing partial output (interrupt again to abort)")
+
+		<-ch
+		removeSpills()
+		finishAudit(AuditInterrupted, errors.New("aborted by a second interrupt"))
+		os.Exit(ExitInterrupted)
+	}()
+}
+
+func isInterrupted() bool {
+	return atomic.LoadInt32(&interrupted) == 1
+}
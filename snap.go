@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+)
+
+// grid is the -snap-grid cell size, in degrees or in meters ("500m"). The
+// zero grid snaps nothing.
+type grid struct {
+	degrees float64
+	meters  float64
+}
+
+func parseGrid(value string) (grid, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return grid{}, nil
+	}
+
+	if strings.HasSuffix(value, "m") {
+		m, err := strconv.ParseFloat(strings.TrimSuffix(value, "m"), 64)
+		if err != nil || m <= 0 {
+			return grid{}, fmt.Errorf("%w: snap grid %q, expected degrees or a positive distance like 500m", ErrBadInput, value)
+		}
+		return grid{meters: m}, nil
+	}
+
+	d, err := strconv.ParseFloat(value, 64)
+	if err != nil || d <= 0 || d > 90 {
+		return grid{}, fmt.Errorf("%w: snap grid %q, expected degrees between 0 and 90 or a distance like 500m", ErrBadInput, value)
+	}
+
+	return grid{degrees: d}, nil
+}
+
+func (g grid) enabled() bool {
+	return g.degrees > 0 || g.meters > 0
+}
+
+// snap moves the lat (x), lng (y) pair to the center of its grid cell.
+// Meter cells keep their width in meters, so longitude steps widen with
+// the cell row's latitude.
+func (g grid) snap(x, y float64) (float64, float64) {
+	if !g.enabled() {
+		return x, y
+	}
+
+	latStep, lngStep := g.degrees, g.degrees
+	if g.meters > 0 {
+		latStep = g.meters / MetersPerDegree
+	}
+	x = cellCenter(x, latStep)
+	if g.meters > 0 {
+		lngStep = latStep / math.Max(math.Cos(x*math.Pi/180), 1e-6)
+	}
+	y = cellCenter(y, lngStep)
+
+	return math.Max(-90, math.Min(90, x)), y
+}
+
+func cellCenter(v, step float64) float64 {
+	return math.Floor(v/step)*step + step/2
+}
+
+// distinctCells counts the distinct positions of points, which after
+// -snap-grid are the occupied cells.
+func distinctCells(points []s2.LatLng) int {
+	cells := map[s2.LatLng]bool{}
+	for _, p := range points {
+		cells[p] = true
+	}
+
+	return len(cells)
+}
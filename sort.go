@@ -0,0 +1,36 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sortRoutes orders routes by column col so later ones draw on top.
+// Values compare numerically when both parse as numbers, as text
+// otherwise. The sort is stable, keeping file order among equal values.
+func sortRoutes(routes []route, col int, desc bool) {
+	value := func(rt route) string {
+		if col < len(rt.Record) {
+			return strings.TrimSpace(rt.Record[col])
+		}
+		return ""
+	}
+
+	less := func(a, b string) bool {
+		fa, errA := strconv.ParseFloat(a, 64)
+		fb, errB := strconv.ParseFloat(b, 64)
+		if errA == nil && errB == nil {
+			return fa < fb
+		}
+		return a < b
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		a, b := value(routes[i]), value(routes[j])
+		if desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StdinSource is the -file value that reads standard input.
+const StdinSource = "-"
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// sourceOpener opens an input given its -file value.
+type sourceOpener interface {
+	Open(src string) (io.ReadCloser, error)
+}
+
+// fileOpener opens local files.
+type fileOpener struct{}
+
+func (fileOpener) Open(src string) (io.ReadCloser, error) {
+	filePath, err := filepath.Abs(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if stat, e := os.Stat(filePath); e == nil && stat.IsDir() {
+		return nil, ErrBadInput
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil || !strings.HasSuffix(strings.ToLower(src), ".gz") {
+		return file, err
+	}
+
+	return gunzip(file)
+}
+
+// stdinOpener streams standard input, which can only be read once: the
+// directive line is looked at through keepOpen rather than a second Open.
+type stdinOpener struct {
+	opened bool
+}
+
+func (o *stdinOpener) Open(string) (io.ReadCloser, error) {
+	if o.opened {
+		return nil, fmt.Errorf("%w: standard input can only be read once", ErrBadInput)
+	}
+	o.opened = true
+
+	return sniffGzip(os.Stdin)
+}
+
+var stdin = &stdinOpener{}
+
+// sniffGzip peeks at the first bytes of r and decompresses it when they
+// are the gzip magic number, otherwise it passes the stream through.
+func sniffGzip(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if !bytes.Equal(head, gzipMagic) {
+		return ioutil.NopCloser(br), nil
+	}
+
+	return gunzip(ioutil.NopCloser(br))
+}
+
+// gzipReadCloser closes both the decompressor and the underlying stream.
+type gzipReadCloser struct {
+	*gzip.Reader
+	src io.Closer
+}
+
+func (g gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.src.Close()
+}
+
+func gunzip(rc io.ReadCloser) (io.ReadCloser, error) {
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("%w: gzip: %v", ErrBadInput, err)
+	}
+
+	return gzipReadCloser{zr, rc}, nil
+}
+
+// httpOpener fetches http(s) URLs.
+type httpOpener struct {
+	client *http.Client
+}
+
+func (o httpOpener) Open(src string) (io.ReadCloser, error) {
+	resp, err := o.client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: fetching %s: %s", ErrBadInput, src, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+func isURL(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
+
+// defaultOpener picks the built-in opener for src.
+func defaultOpener(src string) sourceOpener {
+	if src == StdinSource {
+		return stdin
+	}
+	if isURL(src) {
+		return httpOpener{client: &http.Client{Timeout: 60 * time.Second}}
+	}
+
+	return fileOpener{}
+}
+
+// openedSources are inputs opened ahead of their read, such as the first
+// one whose directive line was looked at, for the read to take over.
+var openedSources = map[string]io.ReadCloser{}
+
+// openedSource is a stream that was partly read ahead, with what was read
+// put back in front.
+type openedSource struct {
+	io.Reader
+	io.Closer
+}
+
+// keepOpen parks r, the content of src, for the next openSource of src.
+func keepOpen(src string, r io.Reader, c io.Closer) {
+	if prev, ok := openedSources[src]; ok {
+		prev.Close()
+	}
+	openedSources[src] = openedSource{r, c}
+}
+
+// takeOpened returns the parked stream of src, if there is one, handing it
+// out once.
+func takeOpened(src string) (io.ReadCloser, bool) {
+	rc, ok := openedSources[src]
+	if ok {
+		delete(openedSources, src)
+	}
+
+	return rc, ok
+}
+
+// openSource opens src with opts.opener, or the default opener for it. An
+// input opened ahead by keepOpen is taken over instead.
+func openSource(src string, opts options) (io.ReadCloser, error) {
+	if rc, ok := takeOpened(src); ok {
+		return rc, nil
+	}
+	if opts.opener != nil {
+		return opts.opener.Open(src)
+	}
+
+	return defaultOpener(src).Open(src)
+}
+
+// InlineSource is the input name of a -data run, used in output names.
+const InlineSource = "inline"
+
+// dataOpener serves the -data flag value as the input. A literal \n in
+// the value separates rows, so examples fit on one shell line.
+type dataOpener struct {
+	content string
+}
+
+func newDataOpener(value string) dataOpener {
+	return dataOpener{content: strings.Replace(value, `\n`, "\n", -1)}
+}
+
+func (o dataOpener) Open(string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(o.content)), nil
+}
+
+// sourceBaseName is the input name without directory or extension, used in
+// output names.
+func sourceBaseName(src string) string {
+	if src == StdinSource {
+		return "stdin"
+	}
+
+	name := src
+	if isURL(src) {
+		if u, err := url.Parse(src); err == nil {
+			name = u.Path
+		}
+	}
+
+	name = strings.TrimSuffix(filepath.Base(name), ".gz")
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mapOpener is a sourceOpener serving inputs from memory.
+type mapOpener map[string]string
+
+func (m mapOpener) Open(src string) (io.ReadCloser, error) {
+	content, ok := m[src]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return ioutil.NopCloser(strings.NewReader(content)), nil
+}
+
+func readSource(t *testing.T, src string, opts options) (string, error) {
+	t.Helper()
+
+	rc, err := openSource(src, opts)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	b, err := ioutil.ReadAll(rc)
+	return string(b), err
+}
+
+func gzipBytes(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestOpenSource(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "routes.csv")
+	packed := filepath.Join(dir, "routes.csv.gz")
+	if err := ioutil.WriteFile(plain, []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(packed, gzipBytes(t, "c,d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/routes.csv" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "e,f\n")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		src     string
+		opener  sourceOpener
+		want    string
+		wantErr bool
+	}{
+		{name: "file", src: plain, want: "a,b\n"},
+		{name: "gzipped file", src: packed, want: "c,d\n"},
+		{name: "directory", src: dir, wantErr: true},
+		{name: "missing file", src: filepath.Join(dir, "missing.csv"), wantErr: true},
+		{name: "url", src: srv.URL + "/routes.csv", want: "e,f\n"},
+		{name: "url not found", src: srv.URL + "/missing.csv", wantErr: true},
+		{name: "custom opener", src: "mem://routes", opener: mapOpener{"mem://routes": "g,h\n"}, want: "g,h\n"},
+	}
+
+	for _, tt := range tests {
+		opts := testOptions(t)
+		opts.opener = tt.opener
+
+		got, err := readSource(t, tt.src, opts)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: read %q, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestDefaultOpener(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: "-", want: "*main.stdinOpener"},
+		{src: "http://example.com/a.csv", want: "main.httpOpener"},
+		{src: "https://example.com/a.csv", want: "main.httpOpener"},
+		{src: "files/sample.csv", want: "main.fileOpener"},
+		{src: "httpdocs/a.csv", want: "main.fileOpener"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprintf("%T", defaultOpener(tt.src)); got != tt.want {
+			t.Errorf("defaultOpener(%q) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestSourceBaseName(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"files/sample.csv", "sample"},
+		{"/tmp/routes.csv.gz", "routes"},
+		{"-", "stdin"},
+		{"https://example.com/exports/routes.csv?day=1", "routes"},
+	}
+
+	for _, tt := range tests {
+		if got := sourceBaseName(tt.src); got != tt.want {
+			t.Errorf("sourceBaseName(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestGunzipBadInput(t *testing.T) {
+	_, err := gunzip(ioutil.NopCloser(strings.NewReader("not gzip")))
+	if !errors.Is(err, ErrBadInput) {
+		t.Errorf("gunzip error = %v, want ErrBadInput", err)
+	}
+}
+
+func TestSniffGzip(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", in: []byte("a,b\n"), want: "a,b\n"},
+		{name: "gzip", in: gzipBytes(t, "a,b\n"), want: "a,b\n"},
+		{name: "empty", in: nil, want: ""},
+		{name: "single byte", in: []byte{0x1f}, want: "\x1f"},
+		{name: "bad gzip header", in: []byte{0x1f, 0x8b, 0x00}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		rc, err := sniffGzip(bytes.NewReader(tt.in))
+		if err != nil {
+			if !tt.wantErr {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		got, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if (err != nil) != tt.wantErr || string(got) != tt.want {
+			t.Errorf("%s: read %q, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestStdinOpener(t *testing.T) {
+	f, err := ioutil.TempFile(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(gzipBytes(t, "a,b\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	defer func(stdin *os.File) { os.Stdin = stdin }(os.Stdin)
+	os.Stdin = f
+
+	o := &stdinOpener{}
+	rc, err := o.Open(StdinSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(got) != "a,b\n" {
+		t.Errorf("read %q, %v", got, err)
+	}
+
+	if _, err := o.Open(StdinSource); !errors.Is(err, ErrBadInput) {
+		t.Errorf("second open error = %v, want ErrBadInput", err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/bmishra/courierInfo/mapview"
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/fogleman/gg"
+	"github.com/golang/geo/s2"
+)
+
+// DefaultSpillAfter is how many markers -spill keeps in memory before the
+// rest go to disk.
+const DefaultSpillAfter = 1000000
+
+// spillRecord is a marker as a spill file holds it, 24 bytes.
+type spillRecord struct {
+	Lat, Lng   float64
+	Size       float32
+	R, G, B, A uint8
+}
+
+// spillFile holds the markers of a -spill run that didn't fit under its
+// threshold, for drawLayer to stream back. Only their position, color and
+// size are kept: IDs, popups and glyphs are dropped with the marker.
+type spillFile struct {
+	after int
+	file  *os.File
+	w     *bufio.Writer
+	err   error
+
+	count   int
+	bounds  s2.Rect
+	maxSize float64
+
+	// last is the marker just spilled, so the set* calls that follow its
+	// addMarker don't keep it in memory after all
+	last *sm.Marker
+}
+
+// spills are the spill files not yet removed, for removeSpills.
+var (
+	spillsMu sync.Mutex
+	spills   = map[*spillFile]bool{}
+)
+
+func newSpillFile(after int) (*spillFile, error) {
+	f, err := ioutil.TempFile("", "courierinfo-spill-")
+	if err != nil {
+		return nil, fmt.Errorf("-spill: %w", err)
+	}
+
+	s := &spillFile{after: after, file: f, w: bufio.NewWriter(f), bounds: s2.EmptyRect()}
+	spillsMu.Lock()
+	spills[s] = true
+	spillsMu.Unlock()
+
+	return s, nil
+}
+
+// add writes m to the file; a write error is kept for draw to report.
+func (s *spillFile) add(m *sm.Marker) {
+	c := color.NRGBAModel.Convert(m.Color).(color.NRGBA)
+	rec := spillRecord{Lat: m.Position.Lat.Degrees(), Lng: m.Position.Lng.Degrees(), Size: float32(m.Size), R: c.R, G: c.G, B: c.B, A: c.A}
+	if s.err == nil {
+		s.err = binary.Write(s.w, binary.LittleEndian, rec)
+	}
+
+	s.count++
+	s.bounds = s.bounds.AddPoint(m.Position)
+	if m.Size > s.maxSize {
+		s.maxSize = m.Size
+	}
+	s.last = m
+}
+
+// draw streams the spilled markers onto dc, scaled to pixelScale like the
+// markers in memory.
+func (s *spillFile) draw(dc *gg.Context, vp Viewport, circles bool) error {
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+	if s.err != nil {
+		return fmt.Errorf("-spill: %w", s.err)
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("-spill: %w", err)
+	}
+
+	r := bufio.NewReader(s.file)
+	for i := 0; i < s.count; i++ {
+		var rec spillRecord
+		if err := binary.Read(r, binary.LittleEndian, &rec); err != nil {
+			return fmt.Errorf("-spill: reading marker %d of %d: %w", i+1, s.count, err)
+		}
+		m := sm.NewMarker(s2.LatLngFromDegrees(rec.Lat, rec.Lng), color.NRGBA{rec.R, rec.G, rec.B, rec.A}, float64(rec.Size)*pixelScale)
+		mapview.DrawMarker(dc, m, vp, circles)
+	}
+
+	_, err := s.file.Seek(0, io.SeekEnd)
+	return err
+}
+
+// remove closes and deletes the file.
+func (s *spillFile) remove() {
+	spillsMu.Lock()
+	defer spillsMu.Unlock()
+	if !spills[s] {
+		return
+	}
+
+	delete(spills, s)
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
+
+// removeSpills deletes every spill file left, before the process exits.
+func removeSpills() {
+	spillsMu.Lock()
+	left := make([]*spillFile, 0, len(spills))
+	for s := range spills {
+		left = append(left, s)
+	}
+	spillsMu.Unlock()
+
+	for _, s := range left {
+		s.remove()
+	}
+}
+
+// spilled reports whether m went to the spill file rather than memory.
+func (l *layer) spilled(m *sm.Marker) bool {
+	return l.spill != nil && l.spill.last == m
+}
+
+// markerCount counts the markers in memory and spilled.
+func (l *layer) markerCount() int {
+	if l.spill == nil {
+		return len(l.markers)
+	}
+
+	return len(l.markers) + l.spill.count
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// StatQuantiles are the distance quantiles stats mode reports.
+var StatQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// StatsExactLimit is how many distances -approx-quantiles still keeps to
+// compare its estimates with the exact values; larger inputs only get the
+// estimates.
+const StatsExactLimit = 100000
+
+// p2Quantile estimates one quantile of a stream in constant memory with the
+// P² algorithm of Jain and Chlamtac: five markers whose heights follow the
+// minimum, the quantile, the maximum and the halfway points between.
+type p2Quantile struct {
+	p       float64
+	count   int
+	heights [5]float64
+	pos     [5]float64 // actual marker positions
+	want    [5]float64 // desired marker positions
+	step    [5]float64 // desired position increments
+}
+
+func newP2Quantile(p float64) *p2Quantile {
+	return &p2Quantile{
+		p:    p,
+		pos:  [5]float64{0, 1, 2, 3, 4},
+		want: [5]float64{0, 2 * p, 4 * p, 2 + 2*p, 4},
+		step: [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+func (q *p2Quantile) add(x float64) {
+	if q.count < 5 {
+		q.heights[q.count] = x
+		q.count++
+		if q.count == 5 {
+			sort.Float64s(q.heights[:])
+		}
+		return
+	}
+	q.count++
+
+	h := &q.heights
+	var k int
+	switch {
+	case x < h[0]:
+		h[0] = x
+		k = 0
+	case x >= h[4]:
+		h[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3 && x >= h[k+1]; k++ {
+		}
+	}
+
+	for i := k + 1; i < 5; i++ {
+		q.pos[i]++
+	}
+	for i := range q.want {
+		q.want[i] += q.step[i]
+	}
+
+	for i := 1; i < 4; i++ {
+		d := q.want[i] - q.pos[i]
+		if (d >= 1 && q.pos[i+1]-q.pos[i] > 1) || (d <= -1 && q.pos[i-1]-q.pos[i] < -1) {
+			d = math.Copysign(1, d)
+			height := q.parabolic(i, d)
+			if height <= h[i-1] || height >= h[i+1] {
+				height = q.linear(i, d)
+			}
+			h[i] = height
+			q.pos[i] += d
+		}
+	}
+}
+
+func (q *p2Quantile) parabolic(i int, d float64) float64 {
+	h, n := q.heights, q.pos
+	return h[i] + d/(n[i+1]-n[i-1])*((n[i]-n[i-1]+d)*(h[i+1]-h[i])/(n[i+1]-n[i])+
+		(n[i+1]-n[i]-d)*(h[i]-h[i-1])/(n[i]-n[i-1]))
+}
+
+func (q *p2Quantile) linear(i int, d float64) float64 {
+	j := i + int(d)
+	return q.heights[i] + d*(q.heights[j]-q.heights[i])/(q.pos[j]-q.pos[i])
+}
+
+// value is the current estimate; below five values it is exact.
+func (q *p2Quantile) value() float64 {
+	if q.count < 5 {
+		values := append([]float64(nil), q.heights[:q.count]...)
+		sort.Float64s(values)
+		return quantile(values, q.p)
+	}
+
+	return q.heights[2]
+}
+
+// quantile interpolates the p quantile of sorted values.
+func quantile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+
+	r := p * float64(len(sorted)-1)
+	i := int(r)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[i] + (r-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// distanceStats collects route distances, in km, for stats mode. Exact
+// quantiles keep every distance; with -approx-quantiles memory stays
+// bounded by the P² estimators and at most StatsExactLimit distances.
+type distanceStats struct {
+	approx bool
+
+	count    int
+	min, max float64
+	total    float64
+
+	exact      []float64
+	overflow   bool // more than StatsExactLimit distances in approx mode
+	estimators []*p2Quantile
+}
+
+func newDistanceStats(approx bool) *distanceStats {
+	st := &distanceStats{approx: approx, min: math.Inf(1), max: math.Inf(-1)}
+	if approx {
+		for _, p := range StatQuantiles {
+			st.estimators = append(st.estimators, newP2Quantile(p))
+		}
+	}
+
+	return st
+}
+
+func (st *distanceStats) add(km float64) {
+	st.count++
+	st.total += km
+	st.min = math.Min(st.min, km)
+	st.max = math.Max(st.max, km)
+
+	for _, e := range st.estimators {
+		e.add(km)
+	}
+
+	if !st.approx || len(st.exact) < StatsExactLimit {
+		st.exact = append(st.exact, km)
+	} else if !st.overflow {
+		st.overflow = true
+		st.exact = nil
+	}
+}
+
+// quantileStat is one reported quantile. Exact is left out when only the
+// estimate is known; Estimate when it wasn't asked for.
+type quantileStat struct {
+	Quantile float64  `json:"quantile"`
+	Exact    *float64 `json:"exact_km,omitempty"`
+	Estimate *float64 `json:"estimate_km,omitempty"`
+}
+
+type statsReport struct {
+	Routes    int            `json:"routes"`
+	MinKm     float64        `json:"min_km"`
+	MeanKm    float64        `json:"mean_km"`
+	MaxKm     float64        `json:"max_km"`
+	Quantiles []quantileStat `json:"quantiles"`
+}
+
+func (st *distanceStats) report() statsReport {
+	r := statsReport{Routes: st.count, MinKm: st.min, MeanKm: st.total / float64(st.count), MaxKm: st.max}
+
+	var sorted []float64
+	if !st.overflow {
+		sorted = append(sorted, st.exact...)
+		sort.Float64s(sorted)
+	}
+
+	for i, p := range StatQuantiles {
+		q := quantileStat{Quantile: p}
+		if !st.overflow {
+			v := quantile(sorted, p)
+			q.Exact = &v
+		}
+		if st.approx {
+			v := st.estimators[i].value()
+			q.Estimate = &v
+		}
+		r.Quantiles = append(r.Quantiles, q)
+	}
+
+	return r
+}
+
+// writeStats writes the distance stats as JSON or plain text.
+func writeStats(w io.Writer, st *distanceStats, format string) error {
+	if st == nil || st.count == 0 {
+		return ErrTooFewRows
+	}
+
+	r := st.report()
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+
+	if _, err := fmt.Fprintf(w, "Routes: %d, distance min: %.3f km, mean: %.3f km, max: %.3f km\n",
+		r.Routes, r.MinKm, r.MeanKm, r.MaxKm); err != nil {
+		return err
+	}
+
+	for _, q := range r.Quantiles {
+		var line string
+		switch {
+		case q.Estimate != nil && q.Exact != nil:
+			line = fmt.Sprintf("p%g: %.3f km estimated, %.3f km exact (%+.2f%%)",
+				100*q.Quantile, *q.Estimate, *q.Exact, relativeError(*q.Estimate, *q.Exact))
+		case q.Estimate != nil:
+			line = fmt.Sprintf("p%g: %.3f km estimated", 100*q.Quantile, *q.Estimate)
+		default:
+			line = fmt.Sprintf("p%g: %.3f km", 100*q.Quantile, *q.Exact)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func relativeError(estimate, exact float64) float64 {
+	if exact == 0 {
+		return 0
+	}
+
+	return 100 * (estimate - exact) / exact
+}
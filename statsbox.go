@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"image"
+)
+
+// statsBoxLines is the run summary -stats-box shows.
+func statsBoxLines(sum *summary) []string {
+	lines := []string{
+		fmt.Sprintf("Plotted: %d", sum.Routes),
+		fmt.Sprintf("Skipped: %d", sum.Skipped),
+	}
+	if sum.Routes > 0 && sum.TotalDistance > 0 {
+		lines = append(lines,
+			fmt.Sprintf("Total: %.1f km", sum.TotalDistance/1000),
+			fmt.Sprintf("Mean: %.1f km", sum.TotalDistance/1000/float64(sum.Routes)))
+	}
+
+	return lines
+}
+
+// drawStatsBox draws the run summary in corner of img, stacked with the
+// other overlays there.
+func drawStatsBox(img image.Image, sum *summary, corner string, stack cornerStack, t theme) image.Image {
+	dc := newOverlay(img)
+
+	lines := statsBoxLines(sum)
+	w := 0.0
+	for _, l := range lines {
+		if lw, _ := measureString(dc, l); lw > w {
+			w = lw
+		}
+	}
+
+	boxW, boxH := w+2*legendPadding, float64(len(lines))*legendLineH+2*legendPadding
+	imgW, imgH := logicalSize(dc)
+	x, y := stack.place(corner, boxW, boxH, imgW, imgH)
+
+	dc.SetColor(t.Panel)
+	dc.DrawRectangle(x, y, boxW, boxH)
+	dc.Fill()
+
+	dc.SetColor(t.Text)
+	for i, l := range lines {
+		dc.DrawStringAnchored(l, x+legendPadding, y+legendPadding+(float64(i)+0.5)*legendLineH, 0, 0.35)
+	}
+
+	return dc.Image()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fogleman/gg"
+)
+
+// Marker styles for -marker-style
+const (
+	MarkerPin    = "pin"
+	MarkerCircle = "circle"
+)
+
+// elementStyle is how drawLayer draws paths and markers where it differs
+// from go-staticmaps.
+type elementStyle struct {
+	Stroke  lineStroke
+	Circles bool // markers as circles centered on the position, not pins
+}
+
+// defaultStyle is the go-staticmaps look.
+var defaultStyle = elementStyle{Stroke: defaultStroke}
+
+// lineStroke is how path ends and corners are drawn.
+type lineStroke struct {
+	Cap  gg.LineCap
+	Join gg.LineJoin
+}
+
+// defaultStroke is the round caps and joins go-staticmaps draws paths with.
+var defaultStroke = lineStroke{Cap: gg.LineCapRound, Join: gg.LineJoinRound}
+
+var lineCaps = map[string]gg.LineCap{
+	"round":  gg.LineCapRound,
+	"butt":   gg.LineCapButt,
+	"square": gg.LineCapSquare,
+}
+
+var lineJoins = map[string]gg.LineJoin{
+	"round": gg.LineJoinRound,
+	"bevel": gg.LineJoinBevel,
+}
+
+// parseStroke parses the -line-cap and -line-join names.
+func parseStroke(capName, joinName string) (lineStroke, error) {
+	c, ok := lineCaps[capName]
+	if !ok {
+		return lineStroke{}, fmt.Errorf("%w: line cap %q, expected round, butt or square", ErrBadInput, capName)
+	}
+
+	j, ok := lineJoins[joinName]
+	if !ok {
+		// gg only strokes round and bevel joins
+		return lineStroke{}, fmt.Errorf("%w: line join %q, expected round or bevel", ErrBadInput, joinName)
+	}
+
+	return lineStroke{Cap: c, Join: j}, nil
+}
+
+func (s lineStroke) apply(dc *gg.Context) {
+	dc.SetLineCap(s.Cap)
+	dc.SetLineJoin(s.Join)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+// theme bundles the colors used for one consistent map look.
+type theme struct {
+	Source      color.RGBA
+	Destination color.RGBA
+	Line        color.RGBA
+	Origin      color.RGBA
+
+	// Background fills -no-basemap renders; a zero alpha keeps them
+	// transparent.
+	Background color.RGBA
+
+	// Text and Panel color the legend overlay.
+	Text  color.RGBA
+	Panel color.NRGBA
+
+	// Tiles names the go-staticmaps tile provider; empty keeps the default.
+	Tiles string
+}
+
+var themes = map[string]theme{
+	"light": {
+		Source:      color.RGBA{0x00, 0xff, 0x00, 0xff},
+		Destination: color.RGBA{0xff, 0x00, 0x00, 0xff},
+		Line:        color.RGBA{0x00, 0x00, 0x00, 0xff},
+		Origin:      color.RGBA{0x00, 0x00, 0xff, 0xff},
+		Text:        color.RGBA{0x00, 0x00, 0x00, 0xff},
+		Panel:       color.NRGBA{0xff, 0xff, 0xff, 0xcc},
+	},
+	"dark": {
+		Source:      color.RGBA{0x4a, 0xde, 0x80, 0xff},
+		Destination: color.RGBA{0xf8, 0x71, 0x71, 0xff},
+		Line:        color.RGBA{0xe5, 0xe5, 0xe5, 0xff},
+		Origin:      color.RGBA{0x60, 0xa5, 0xfa, 0xff},
+		Background:  color.RGBA{0x1e, 0x1e, 0x1e, 0xff},
+		Text:        color.RGBA{0xff, 0xff, 0xff, 0xff},
+		Panel:       color.NRGBA{0x00, 0x00, 0x00, 0xcc},
+		Tiles:       "carto-dark",
+	},
+	"print": {
+		Source:      color.RGBA{0x66, 0x66, 0x66, 0xff},
+		Destination: color.RGBA{0x00, 0x00, 0x00, 0xff},
+		Line:        color.RGBA{0x33, 0x33, 0x33, 0xff},
+		Origin:      color.RGBA{0x00, 0x00, 0x00, 0xff},
+		Background:  color.RGBA{0xff, 0xff, 0xff, 0xff},
+		Text:        color.RGBA{0x00, 0x00, 0x00, 0xff},
+		Panel:       color.NRGBA{0xff, 0xff, 0xff, 0xff},
+		Tiles:       "carto-light",
+	},
+}
+
+// themeNames lists the known themes for messages.
+func themeNames() string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// parseTheme looks up a theme and applies every non-empty color override
+// in order source, destination, line, background.
+func parseTheme(name string, overrides ...string) (theme, error) {
+	t, ok := themes[name]
+	if !ok {
+		return t, fmt.Errorf("%w: unknown theme %q, expected one of %s", ErrBadInput, name, themeNames())
+	}
+
+	targets := []*color.RGBA{&t.Source, &t.Destination, &t.Line, &t.Background}
+	for i, value := range overrides {
+		if value == "" || i >= len(targets) {
+			continue
+		}
+
+		c, err := parseColor(value)
+		if err != nil {
+			return t, err
+		}
+		*targets[i] = c
+	}
+
+	return t, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+	"time"
+
+	sm "github.com/flopp/go-staticmaps"
+)
+
+// localTileProviders are looked up before the go-staticmaps ones, so the
+// render tests can draw over a stub tile server.
+var localTileProviders = map[string]*sm.TileProvider{}
+
+// tileProviderNames lists the go-staticmaps providers for messages.
+func tileProviderNames() string {
+	var names []string
+	for name := range sm.GetTileProviders() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// lookupTileProvider returns the named provider. An empty name means the
+// go-staticmaps default and returns nil.
+func lookupTileProvider(name string) (*sm.TileProvider, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	if tp, ok := localTileProviders[name]; ok {
+		return tp, nil
+	}
+
+	tp, ok := sm.GetTileProviders()[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown tile provider %q, expected one of %s", ErrBadInput, name, tileProviderNames())
+	}
+
+	return tp, nil
+}
+
+// renderTiles renders the layer over map tiles from -tiles, or the theme's
+// provider. If that fails and -fallback-tiles is set, the render is tried
+// once more with the fallback; if that fails too the original error is
+// returned.
+func renderTiles(lyr *layer, vp Viewport, opts options) (image.Image, error) {
+	name := opts.tiles
+	if name == "" {
+		name = opts.theme.Tiles
+		if _, err := lookupTileProvider(name); err != nil {
+			if opts.verbose {
+				fmt.Println(fmt.Sprintf("Tile provider %q not available, using the default", name))
+			}
+			name = ""
+		}
+	}
+
+	img, err := renderRetrying(lyr, vp, name, opts)
+	if err == nil || opts.fallbackTiles == "" || errors.Is(err, ErrDeadline) {
+		if err == nil && opts.verbose {
+			fmt.Println(fmt.Sprintf("Rendered with tile provider %s", providerLabel(name)))
+		}
+		return img, err
+	}
+
+	if opts.verbose {
+		fmt.Println(fmt.Sprintf("Tile provider %s failed: %v, retrying with %s", providerLabel(name), err, opts.fallbackTiles))
+	}
+
+	img, fallbackErr := renderRetrying(lyr, vp, opts.fallbackTiles, opts)
+	if fallbackErr != nil {
+		if opts.verbose {
+			fmt.Println(fmt.Sprintf("Tile provider %s failed: %v", opts.fallbackTiles, fallbackErr))
+		}
+		return nil, err
+	}
+
+	if opts.verbose {
+		fmt.Println(fmt.Sprintf("Rendered with tile provider %s", opts.fallbackTiles))
+	}
+	return img, nil
+}
+
+// RetryBackoff is the wait before the first -retries attempt; it doubles
+// for each further one up to RetryBackoffMax.
+const (
+	RetryBackoff    = time.Second
+	RetryBackoffMax = 30 * time.Second
+)
+
+// renderRetrying renders over the named provider, retrying a failed render
+// -retries times with exponential backoff. Each attempt builds a fresh
+// context. ErrDeadline is never retried.
+func renderRetrying(lyr *layer, vp Viewport, name string, opts options) (image.Image, error) {
+	wait := RetryBackoff
+	for attempt := 1; ; attempt++ {
+		ctx := newMapContext(lyr, vp)
+		if tp, _ := lookupTileProvider(name); tp != nil {
+			ctx.SetTileProvider(tp)
+		}
+
+		img, err := renderBefore(ctx)
+		if err == nil || attempt > opts.retries || errors.Is(err, ErrDeadline) || isInterrupted() {
+			return img, err
+		}
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			return nil, err
+		}
+
+		if opts.verbose {
+			fmt.Println(fmt.Sprintf("Render attempt %d of %d with %s failed: %v, retrying in %s",
+				attempt, opts.retries+1, providerLabel(name), err, wait))
+		}
+		time.Sleep(wait)
+
+		wait *= 2
+		if wait > RetryBackoffMax {
+			wait = RetryBackoffMax
+		}
+	}
+}
+
+func providerLabel(name string) string {
+	if name == "" {
+		return "default"
+	}
+
+	return name
+}
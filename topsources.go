@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s2"
+)
+
+// sourceCount is one rounded pickup location and how many routes start
+// there.
+type sourceCount struct {
+	Lat   float64 `json:"lat"`
+	Lng   float64 `json:"lng"`
+	Count int     `json:"count"`
+}
+
+// roundTo rounds v to the given number of decimals.
+func roundTo(v float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(v*scale) / scale
+}
+
+// topSources counts the sources grouped at the given precision and returns
+// the n busiest, ties broken by position so the output is stable.
+func topSources(sources []s2.LatLng, n int, prec precision) []sourceCount {
+	type key struct{ lat, lng float64 }
+
+	counts := map[key]int{}
+	for _, ll := range sources {
+		lat, lng := prec.round(ll)
+		counts[key{lat, lng}]++
+	}
+
+	top := make([]sourceCount, 0, len(counts))
+	for k, c := range counts {
+		top = append(top, sourceCount{Lat: k.lat, Lng: k.lng, Count: c})
+	}
+
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		if top[i].Lat != top[j].Lat {
+			return top[i].Lat < top[j].Lat
+		}
+		return top[i].Lng < top[j].Lng
+	})
+
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+
+	return top
+}
+
+// writeTopSources writes the busiest sources as JSON or plain text.
+func writeTopSources(w io.Writer, sources []s2.LatLng, n int, prec precision, format string) error {
+	if len(sources) == 0 {
+		return ErrTooFewRows
+	}
+
+	top := topSources(sources, n, prec)
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(top)
+	}
+
+	for i, s := range top {
+		if _, err := fmt.Fprintf(w, "%d. %.*f,%.*f: %d\n", i+1, prec.decimals, s.Lat, prec.decimals, s.Lng, s.Count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
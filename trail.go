@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s2"
+)
+
+// trailColor gives shipment i its own hue, stepping by the golden angle
+// so neighbouring shipments get far apart colors however many there are.
+func trailColor(i int) color.RGBA {
+	h := math.Mod(float64(i)*137.508, 360) / 60
+	x := 1 - math.Abs(math.Mod(h, 2)-1)
+
+	var r, g, b float64
+	switch int(h) {
+	case 0:
+		r, g = 1, x
+	case 1:
+		r, g = x, 1
+	case 2:
+		g, b = 1, x
+	case 3:
+		g, b = x, 1
+	case 4:
+		r, b = x, 1
+	default:
+		r, b = 1, x
+	}
+
+	// 0.8 saturation and value keep the colors readable on the tiles
+	scale := func(v float64) uint8 { return uint8(math.Round(255 * 0.8 * (0.2 + 0.8*v))) }
+	return color.RGBA{scale(r), scale(g), scale(b), 0xff}
+}
+
+// addTrails draws trail mode: rows sharing an -id-col shipment ID are its
+// successive scans, joined in -time-col order (file order without it)
+// into one path through each row's source and destination.
+func (p *plotter) addTrails(routes []route) {
+	opts := p.opts
+
+	var order []string
+	shipments := map[string][]route{}
+	for _, rt := range routes {
+		id := ""
+		if opts.idCol < len(rt.Record) {
+			id = strings.TrimSpace(rt.Record[opts.idCol])
+		}
+		if id == "" {
+			p.pass(rt.Row, "no -id-col shipment ID")
+			continue
+		}
+
+		if _, ok := shipments[id]; !ok {
+			order = append(order, id)
+		}
+		shipments[id] = append(shipments[id], rt)
+	}
+
+	plotted := 0
+	for i, id := range order {
+		scans := shipments[id]
+		if opts.timeCol >= 0 {
+			sortRoutes(scans, opts.timeCol, false)
+		}
+
+		// records holds the scan each point comes from, for -size-col
+		var points []s2.LatLng
+		var records [][]string
+		for _, rt := range scans {
+			for _, ll := range []s2.LatLng{rt.Src, rt.Dst} {
+				if len(points) == 0 || points[len(points)-1] != ll {
+					points = append(points, ll)
+					records = append(records, rt.Record)
+				}
+			}
+		}
+
+		if markerCapReached(p.lyr, len(points), scans[0].Row, opts) {
+			break
+		}
+
+		c := trailColor(i)
+		for j, ll := range points {
+			size := opts.markerSize
+			if j == 0 || j == len(points)-1 {
+				size *= 1.5
+			}
+			m := sm.NewMarker(ll, c, size)
+			p.addMarker(m, records[j])
+			p.lyr.setID(m, fmt.Sprintf("%s.%d", id, j))
+
+			if j > 0 {
+				p.sum.TotalDistance += distanceMeters(points[j-1], ll, opts.distanceModel)
+			}
+		}
+
+		segments := [][]s2.LatLng{points}
+		if opts.wrap {
+			segments = splitPolyline(points)
+		}
+		for _, segment := range segments {
+			p.lyr.addPath(sm.NewPath(segment, c, 2))
+		}
+
+		for _, rt := range scans {
+			p.diagnose(routeDiag(rt, nil))
+		}
+		p.lyr.sources = append(p.lyr.sources, points[0])
+		p.sum.Routes += len(scans)
+		plotted++
+	}
+
+	fmt.Println(fmt.Sprintf("Shipments: %d, scans: %d", plotted, p.sum.Routes))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestTrailSizeCol(t *testing.T) {
+	at := func(lat, lng float64) s2.LatLng { return s2.LatLngFromDegrees(lat, lng) }
+
+	tests := []struct {
+		name   string
+		routes []route
+		sizes  []float64 // of the markers in order
+	}{
+		{
+			name: "each point sized by its scan",
+			routes: []route{
+				{Row: 1, Record: []string{"s1", "1"}, Src: at(1, 100), Dst: at(2, 101)},
+				{Row: 2, Record: []string{"s1", "3"}, Src: at(2, 101), Dst: at(3, 102)},
+			},
+			sizes: []float64{2, 2, 16},
+		},
+		{
+			name: "non-numeric size keeps the trail sizes",
+			routes: []route{
+				{Row: 1, Record: []string{"s1", "n/a"}, Src: at(1, 100), Dst: at(2, 101)},
+				{Row: 2, Record: []string{"s2", "7"}, Src: at(5, 100), Dst: at(6, 101)},
+			},
+			sizes: []float64{6, 6, 16, 16},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t)
+			opts.mode = "trail"
+			opts.idCol = 0
+			opts.sizeCol = 1
+
+			p := newPlotter(opts)
+			p.addTrails(tt.routes)
+			p.finish()
+
+			if len(p.lyr.markers) != len(tt.sizes) {
+				t.Fatalf("%d markers, want %d", len(p.lyr.markers), len(tt.sizes))
+			}
+			for i, m := range p.lyr.markers {
+				if m.Size != tt.sizes[i] {
+					t.Errorf("marker %d size %g, want %g", i, m.Size, tt.sizes[i])
+				}
+			}
+		})
+	}
+}
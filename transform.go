@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// coordTransform converts a pair of input coordinates in some projected
+// system to a WGS84 lat, lng pair in degrees.
+type coordTransform interface {
+	Transform(a, b float64) (float64, float64, error)
+}
+
+// parseTransform parses a -transform spec such as "utm:43N".
+func parseTransform(spec string) (coordTransform, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%w: transform %q, expected kind:params", ErrBadInput, spec)
+	}
+
+	switch strings.ToLower(parts[0]) {
+	case "utm":
+		return parseUTMZone(parts[1])
+	}
+
+	return nil, fmt.Errorf("%w: unknown transform %q", ErrBadInput, parts[0])
+}
+
+// utmTransform converts "easting,northing" in meters within a UTM zone.
+type utmTransform struct {
+	zone  int
+	north bool
+}
+
+// parseUTMZone parses a zone like "43N" or "50s".
+func parseUTMZone(zone string) (utmTransform, error) {
+	zone = strings.ToUpper(strings.TrimSpace(zone))
+	if zone == "" {
+		return utmTransform{}, fmt.Errorf("%w: empty UTM zone", ErrBadInput)
+	}
+
+	hemisphere := zone[len(zone)-1]
+	if hemisphere != 'N' && hemisphere != 'S' {
+		return utmTransform{}, fmt.Errorf("%w: UTM zone %q needs an N or S hemisphere", ErrBadInput, zone)
+	}
+
+	n, err := strconv.Atoi(zone[:len(zone)-1])
+	if err != nil || n < 1 || n > 60 {
+		return utmTransform{}, fmt.Errorf("%w: invalid UTM zone %q", ErrBadInput, zone)
+	}
+
+	return utmTransform{zone: n, north: hemisphere == 'N'}, nil
+}
+
+// Transform implements the inverse transverse mercator series (Snyder,
+// USGS Professional Paper 1395) on the WGS84 ellipsoid.
+func (t utmTransform) Transform(easting, northing float64) (float64, float64, error) {
+	const k0 = 0.9996
+
+	e2 := wgs84F * (2 - wgs84F)
+	ep2 := e2 / (1 - e2)
+
+	x := easting - 500000.0
+	y := northing
+	if !t.north {
+		y -= 10000000.0
+	}
+
+	m := y / k0
+	mu := m / (wgs84A * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+	phi1 := mu +
+		(3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+		(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+		(151*math.Pow(e1, 3)/96)*math.Sin(6*mu) +
+		(1097*math.Pow(e1, 4)/512)*math.Sin(8*mu)
+
+	sinPhi, cosPhi := math.Sincos(phi1)
+	tanPhi := sinPhi / cosPhi
+
+	c1 := ep2 * cosPhi * cosPhi
+	t1 := tanPhi * tanPhi
+	n1 := wgs84A / math.Sqrt(1-e2*sinPhi*sinPhi)
+	r1 := wgs84A * (1 - e2) / math.Pow(1-e2*sinPhi*sinPhi, 1.5)
+	d := x / (n1 * k0)
+
+	lat := phi1 - (n1*tanPhi/r1)*(d*d/2-
+		(5+3*t1+10*c1-4*c1*c1-9*ep2)*math.Pow(d, 4)/24+
+		(61+90*t1+298*c1+45*t1*t1-252*ep2-3*c1*c1)*math.Pow(d, 6)/720)
+
+	lng := (d - (1+2*t1+c1)*math.Pow(d, 3)/6 +
+		(5-2*c1+28*t1-3*c1*c1+8*ep2+24*t1*t1)*math.Pow(d, 5)/120) / cosPhi
+
+	lng0 := float64(t.zone-1)*6 - 180 + 3
+	return lat * 180 / math.Pi, lng0 + lng*180/math.Pi, nil
+}
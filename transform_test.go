@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseTransform(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    coordTransform
+		wantErr bool
+	}{
+		{spec: "utm:48S", want: utmTransform{zone: 48, north: false}},
+		{spec: "UTM:43n", want: utmTransform{zone: 43, north: true}},
+		{spec: "utm:1N", want: utmTransform{zone: 1, north: true}},
+		{spec: "utm:61N", wantErr: true},
+		{spec: "utm:0S", wantErr: true},
+		{spec: "utm:48", wantErr: true},
+		{spec: "utm:", wantErr: true},
+		{spec: "utm", wantErr: true},
+		{spec: "lambert:1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTransform(tt.spec)
+		if tt.wantErr {
+			if !errors.Is(err, ErrBadInput) {
+				t.Errorf("parseTransform(%q) error = %v, want ErrBadInput", tt.spec, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseTransform(%q) = %v, %v, want %v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestUTMTransform(t *testing.T) {
+	tests := []struct {
+		name              string
+		zone              utmTransform
+		easting, northing float64
+		lat, lng          float64
+		tol               float64
+	}{
+		{"equator on central meridian", utmTransform{zone: 48}, 500000, 10000000, 0, 105, 1e-9},
+		{"north equator", utmTransform{zone: 31, north: true}, 500000, 0, 0, 3, 1e-9},
+		{"Jakarta", utmTransform{zone: 48}, 702690, 9316930, -6.1754, 106.8272, 0.01},
+	}
+
+	for _, tt := range tests {
+		lat, lng, err := tt.zone.Transform(tt.easting, tt.northing)
+		if err != nil || math.Abs(lat-tt.lat) > tt.tol || math.Abs(lng-tt.lng) > tt.tol {
+			t.Errorf("%s: Transform = %v, %v, %v, want %v, %v", tt.name, lat, lng, err, tt.lat, tt.lng)
+		}
+	}
+}
+
+func TestParseLocationTransform(t *testing.T) {
+	opts := testOptions(t)
+	opts.transform = utmTransform{zone: 48}
+
+	lat, lng, err := parseLocation("702690,9316930", opts)
+	if err != nil || math.Abs(lat+6.1754) > 0.01 || math.Abs(lng-106.8272) > 0.01 {
+		t.Errorf("parseLocation = %v, %v, %v", lat, lng, err)
+	}
+
+	opts.transform = utmTransform{zone: 31, north: true}
+	if _, _, err := parseLocation("500000,0", opts); !errors.Is(err, ErrLatLongOutOfRange) {
+		t.Errorf("parseLocation outside Indonesia error = %v, want ErrLatLongOutOfRange", err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"strings"
+)
+
+// Line styles of -transport-styles
+var lineStyles = map[string][]float64{
+	"solid":  {},
+	"dashed": {6, 4},
+	"dotted": {2, 3},
+}
+
+// transportStyle is the line look of one mode of transport.
+type transportStyle struct {
+	Color color.RGBA
+	Style string
+}
+
+// parseTransportStyles parses "mode:color[:style]" entries such as
+// "bike:green,van:#1f77b4:dashed,air:red:dotted". Like -group-colors, a
+// value starting with @ names a file holding the entries.
+func parseTransportStyles(spec string) (map[string]transportStyle, error) {
+	if strings.HasPrefix(spec, "@") {
+		data, err := ioutil.ReadFile(spec[1:])
+		if err != nil {
+			return nil, err
+		}
+		spec = strings.Replace(string(data), "\n", ",", -1)
+	}
+
+	styles := map[string]transportStyle{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%w: transport style %q, expected mode:color or mode:color:style", ErrBadInput, entry)
+		}
+
+		c, err := parseColor(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		style := "solid"
+		if len(parts) == 3 {
+			style = strings.ToLower(strings.TrimSpace(parts[2]))
+			if _, ok := lineStyles[style]; !ok {
+				return nil, fmt.Errorf("%w: transport style %q, style must be solid, dashed or dotted", ErrBadInput, entry)
+			}
+		}
+
+		styles[normalizeTransport(parts[0])] = transportStyle{Color: c, Style: style}
+	}
+
+	return styles, nil
+}
+
+func normalizeTransport(mode string) string {
+	return strings.ToLower(strings.TrimSpace(mode))
+}
+
+// transport returns the style of the row's -transport-col mode and
+// records it for the legend on first use.
+func (p *plotter) transport(record []string) (transportStyle, bool) {
+	col := p.opts.transportCol
+	if col < 0 || col >= len(record) {
+		return transportStyle{}, false
+	}
+
+	mode := normalizeTransport(record[col])
+	style, ok := p.opts.transportStyles[mode]
+	if !ok {
+		return style, false
+	}
+
+	if !p.transportSeen[mode] {
+		if p.transportSeen == nil {
+			p.transportSeen = map[string]bool{}
+		}
+		p.transportSeen[mode] = true
+
+		label := mode
+		if style.Style != "solid" {
+			label += " (" + style.Style + ")"
+		}
+		p.transportLegend = append(p.transportLegend, legendEntry{Label: label, Color: style.Color})
+	}
+
+	return style, true
+}
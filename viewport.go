@@ -0,0 +1,14 @@
+package main
+
+import "github.com/bmishra/courierInfo/mapview"
+
+// Map geometry
+const (
+	MapWidth  = 600
+	MapHeight = 400
+	TileSize  = mapview.TileSize
+	MaxZoom   = mapview.MaxZoom
+)
+
+// Viewport is the web-mercator view of the map, see the mapview package.
+type Viewport = mapview.Viewport
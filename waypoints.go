@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s2"
+)
+
+// WaypointSeparator separates the stops within a -waypoints-col cell.
+const WaypointSeparator = "|"
+
+// parseWaypoints parses an ordered, pipe separated list of coordinates.
+// Each stop is validated on its own; invalid stops are dropped and returned
+// as errors so the rest of the route can still be drawn.
+func parseWaypoints(cell string, opts options) ([]s2.LatLng, []error) {
+	var stops []s2.LatLng
+	var errs []error
+
+	for i, part := range strings.Split(cell, WaypointSeparator) {
+		x, y, err := parseLocation(part, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("waypoint %d: %w", i+1, err))
+			continue
+		}
+		x, y = opts.snapGrid.snap(x, y)
+		stops = append(stops, s2.LatLngFromDegrees(x, y))
+	}
+
+	return stops, errs
+}
+
+// addWaypoints adds the multi-stop route of a row with a marker at every
+// stop. It reports false when the -max-markers cap stops the route from
+// being added.
+func (p *plotter) addWaypoints(record []string, row int) bool {
+	opts := p.opts
+
+	cell := ""
+	if opts.waypointsCol < len(record) {
+		cell = record[opts.waypointsCol]
+	}
+
+	stops, errs := parseWaypoints(cell, opts)
+	if opts.verbose {
+		for _, err := range errs {
+			fmt.Println(fmt.Sprintf("%s: dropped %v", p.rowLabel(row), err))
+		}
+	}
+
+	d := diagRow{Row: row, Status: StatusPlotted}
+	for _, stop := range stops {
+		d.Stops = append(d.Stops, *diagPoint(stop))
+	}
+	if len(errs) > 0 {
+		d.Reason = fmt.Sprintf("%d waypoints dropped", len(errs))
+	}
+
+	if len(stops) < 2 {
+		p.sum.Skipped++
+		p.sum.noteLeftOut("fewer than 2 valid waypoints")
+		if opts.errorRows != nil {
+			opts.errorRows.add(p.raw, fmt.Sprintf("%d valid waypoints", len(stops)))
+		}
+		if opts.verbose {
+			fmt.Println(fmt.Sprintf("%s: skipped, %d valid waypoints", p.rowLabel(row), len(stops)))
+		}
+		d.Status = StatusSkipped
+		d.Reason = fmt.Sprintf("%d valid waypoints", len(stops))
+		p.diagnose(d)
+		return true
+	}
+
+	if outsideMask(opts.mask, stops...) {
+		p.sum.Outside++
+		p.pass(row, "outside -mask")
+		return true
+	}
+
+	if markerCapReached(p.lyr, len(stops), row, opts) {
+		return false
+	}
+	p.diagnose(d)
+
+	for i, stop := range stops {
+		// intermediate stops take the theme's Origin color, blue in the
+		// light theme
+		c := opts.theme.Origin
+		if i == 0 {
+			c = opts.theme.Source
+		} else if i == len(stops)-1 {
+			c = opts.theme.Destination
+		}
+		m := sm.NewMarker(stop, c, opts.markerSize)
+		p.addMarker(m, record)
+		if i == 0 && opts.roleStyle == RoleFillHollow {
+			p.lyr.setHollow(m)
+		}
+		p.lyr.setID(m, fmt.Sprintf("%d.%d", row, i))
+
+		if i > 0 {
+			p.sum.TotalDistance += distanceMeters(stops[i-1], stop, opts.distanceModel)
+		}
+	}
+	p.sum.Routes++
+
+	lineColor := opts.theme.Line
+	if opts.fileColors != nil {
+		lineColor = p.noteFile(p.file)
+	}
+	p.lyr.addPath(sm.NewPath(stops, lineColor, 1.0))
+	if opts.hopLabels {
+		p.lyr.noteHops(stops)
+	}
+	return true
+}
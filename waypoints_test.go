@@ -0,0 +1,84 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestWaypointsSizeCol(t *testing.T) {
+	tests := []struct {
+		name  string
+		rows  [][]string
+		sizes []float64 // of the markers in order
+	}{
+		{
+			name:  "one value scales to -size-max",
+			rows:  [][]string{{"r1", "10", "1,100|2,101|3,102"}},
+			sizes: []float64{16, 16, 16},
+		},
+		{
+			name: "two routes",
+			rows: [][]string{
+				{"r1", "10", "1,100|2,101"},
+				{"r2", "30", "1,100|2,101|3,102"},
+			},
+			sizes: []float64{2, 2, 16, 16, 16},
+		},
+		{
+			name: "missing size keeps the base size",
+			rows: [][]string{
+				{"r1", "", "1,100|2,101"},
+				{"r2", "5", "1,100|2,101"},
+			},
+			sizes: []float64{4, 4, 16, 16},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t)
+			opts.waypointsCol = 2
+			opts.sizeCol = 1
+
+			p := newPlotter(opts)
+			for i, row := range tt.rows {
+				if !p.addWaypoints(row, i+1) {
+					t.Fatalf("row %d not added", i+1)
+				}
+			}
+			p.finish()
+
+			if len(p.lyr.markers) != len(tt.sizes) {
+				t.Fatalf("%d markers, want %d", len(p.lyr.markers), len(tt.sizes))
+			}
+			for i, m := range p.lyr.markers {
+				if m.Size != tt.sizes[i] {
+					t.Errorf("marker %d size %g, want %g", i, m.Size, tt.sizes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWaypointsThemeColors(t *testing.T) {
+	for _, name := range []string{"light", "dark", "print"} {
+		opts := testOptions(t)
+		opts.waypointsCol = 0
+		opts.theme, _ = parseTheme(name, "", "", "#123456")
+
+		p := newPlotter(opts)
+		if !p.addWaypoints([]string{"1,100|2,101|3,102"}, 1) {
+			t.Fatalf("%s: row not added", name)
+		}
+
+		want := []color.RGBA{opts.theme.Source, opts.theme.Origin, opts.theme.Destination}
+		for i, m := range p.lyr.markers {
+			if m.Color != want[i] {
+				t.Errorf("%s: marker %d color %v, want %v", name, i, m.Color, want[i])
+			}
+		}
+		if len(p.lyr.paths) != 1 || p.lyr.paths[0].Color != (color.RGBA{0x12, 0x34, 0x56, 0xff}) {
+			t.Errorf("%s: paths %v, want one in the -line-color", name, p.lyr.paths)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build webp
+// +build webp
+
+package main
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// WebPAvailable reports whether this build can write -format webp.
+const WebPAvailable = true
+
+// encodeWebP encodes img lossily at quality 1-100, or losslessly at 100.
+func encodeWebP(w io.Writer, img image.Image, quality int) error {
+	return webp.Encode(w, img, &webp.Options{Lossless: quality >= 100, Quality: float32(quality)})
+}
@@ -0,0 +1,17 @@
+//go:build !webp
+// +build !webp
+
+package main
+
+import (
+	"image"
+	"io"
+)
+
+// WebPAvailable reports whether this build can write -format webp. The
+// encoder needs cgo and libwebp, so it is only built with -tags webp.
+const WebPAvailable = false
+
+func encodeWebP(io.Writer, image.Image, int) error {
+	return ErrNoWebP
+}
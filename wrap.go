@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// crossesAntimeridian reports whether the short way from a to b crosses
+// the ±180° meridian.
+func crossesAntimeridian(a, b s2.LatLng) bool {
+	return math.Abs(b.Lng.Degrees()-a.Lng.Degrees()) > 180
+}
+
+// splitAntimeridian returns the segments of the a→b route taking the short
+// way around the globe. A crossing route is split at ±180° into two
+// segments so neither is drawn across the whole map.
+func splitAntimeridian(a, b s2.LatLng) [][]s2.LatLng {
+	if !crossesAntimeridian(a, b) {
+		return [][]s2.LatLng{{a, b}}
+	}
+
+	lat1, lng1 := a.Lat.Degrees(), a.Lng.Degrees()
+	lat2, lng2 := b.Lat.Degrees(), b.Lng.Degrees()
+
+	// unwrap b so the route is continuous, then find where it meets 180°
+	edge := 180.0
+	if lng1 < 0 {
+		edge = -180.0
+		lng2 -= 360
+	} else {
+		lng2 += 360
+	}
+
+	t := (edge - lng1) / (lng2 - lng1)
+	lat := lat1 + t*(lat2-lat1)
+
+	return [][]s2.LatLng{
+		{a, s2.LatLngFromDegrees(lat, edge)},
+		{s2.LatLngFromDegrees(lat, -edge), b},
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestSplitAntimeridian(t *testing.T) {
+	ll := s2.LatLngFromDegrees
+
+	tests := []struct {
+		name string
+		a, b s2.LatLng
+		want [][][2]float64
+	}{
+		{
+			name: "short route is kept",
+			a:    ll(-6, 106), b: ll(1, 120),
+			want: [][][2]float64{{{-6, 106}, {1, 120}}},
+		},
+		{
+			name: "eastward crossing",
+			a:    ll(-10, 170), b: ll(10, -170),
+			want: [][][2]float64{{{-10, 170}, {0, 180}}, {{0, -180}, {10, -170}}},
+		},
+		{
+			name: "westward crossing",
+			a:    ll(20, -175), b: ll(-10, 175),
+			want: [][][2]float64{{{20, -175}, {5, -180}}, {{5, 180}, {-10, 175}}},
+		},
+	}
+
+	for _, tt := range tests {
+		got := splitAntimeridian(tt.a, tt.b)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %d segments, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i, segment := range got {
+			for j, p := range segment {
+				w := tt.want[i][j]
+				if math.Abs(p.Lat.Degrees()-w[0]) > 1e-9 || math.Abs(p.Lng.Degrees()-w[1]) > 1e-9 {
+					t.Errorf("%s: segment %d point %d = %v, want %v", tt.name, i, j, p, w)
+				}
+			}
+		}
+	}
+}
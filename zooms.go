@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseZoomLevels parses the -zoom-levels list, e.g. "4,8,12", into
+// ascending levels without repeats.
+func parseZoomLevels(value string) ([]int, error) {
+	seen := map[int]bool{}
+	var levels []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		z, err := strconv.Atoi(part)
+		if err != nil || z < 0 || z > MaxZoom {
+			return nil, fmt.Errorf("%w: -zoom-levels %q, expected zooms between 0 and %d", ErrBadInput, part, MaxZoom)
+		}
+		if !seen[z] {
+			seen[z] = true
+			levels = append(levels, z)
+		}
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("%w: zooms mode needs -zoom-levels, e.g. 4,8,12", ErrBadInput)
+	}
+
+	sort.Ints(levels)
+	return levels, nil
+}
+
+// zoomPath names the image of one zoom level: -o with the zoom before its
+// extension, or a default name with it.
+func zoomPath(baseName string, zoom int, sum *summary, opts options) (string, error) {
+	if opts.output != "" {
+		ext := filepath.Ext(opts.output)
+		return fmt.Sprintf("%s-z%d%s", strings.TrimSuffix(opts.output, ext), zoom, ext), nil
+	}
+
+	return outputPath(fmt.Sprintf("img-%s-zooms-%d-%d-z%d%s.%s", baseName, sum.RowCount, time.Now().Unix(), zoom, scaleSuffix(), opts.imageFormat), opts)
+}
+
+// renderZooms draws zooms mode: the same markers rendered once per
+// -zoom-levels level, all around the center the data fits at, for a
+// drill-down set of images. Levels render lowest first; each view lies
+// within the one before, so -clip-to-view only drops markers none of the
+// remaining renders show.
+func renderZooms(lyr *layer, sum *summary, baseName string, opts options) (*fileResult, error) {
+	vp, err := frame(lyr, opts)
+	if err != nil {
+		return nil, err
+	}
+	center := vp.Center
+
+	var first *fileResult
+	var lines []string
+	for _, z := range opts.zoomLevels {
+		o := opts
+		o.fixedZoom = z
+		o.center = &center
+		o.output, err = zoomPath(baseName, z, sum, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := renderImage(lyr, sum, baseName, "", o)
+		if err != nil {
+			return nil, fmt.Errorf("zoom %d: %w", z, err)
+		}
+		if first == nil {
+			first = res
+		}
+		lines = append(lines, fmt.Sprintf("  zoom %d: %s", z, res.Path))
+
+		if isInterrupted() || deadlinePassed() {
+			break
+		}
+	}
+
+	fmt.Println(fmt.Sprintf("\nZoom levels: %d, center %f,%f", len(lines), center.Lat.Degrees(), center.Lng.Degrees()))
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+
+	return first, nil
+}